@@ -0,0 +1,271 @@
+/*
+Copyright © 2022 ansavin
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ansavin/konfig/internal"
+)
+
+// addCmd groups commands creating kubeconfig entries
+var addCmd = &cobra.Command{
+	Use:   "add",
+	Short: "adds clusters, users and contexts to kubeconfig",
+	Args:  usageArgs(cobra.NoArgs),
+}
+
+// addClusterCmd represents command to add cluster
+var addClusterCmd = &cobra.Command{
+	Use:   "cluster <name>",
+	Short: "adds cluster to kubeconfig",
+	Long: `Adds cluster with given --server to current kubeconfig. CA certificate
+	set by --ca-file is referenced by absolute path or inlined with --embed.
+	Existing cluster is replaced only with --force
+		  `,
+	Args: usageArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		server, err := cmd.Flags().GetString(internal.OptionServer)
+		if err != nil {
+			return err
+		}
+
+		caFile, err := cmd.Flags().GetString(internal.OptionCAFile)
+		if err != nil {
+			return err
+		}
+
+		insecure, err := cmd.Flags().GetBool(internal.OptionInsecure)
+		if err != nil {
+			return err
+		}
+
+		embed, err := cmd.Flags().GetBool(internal.OptionEmbed)
+		if err != nil {
+			return err
+		}
+
+		cluster, err := internal.NewCluster(internal.ClusterOptions{
+			Server: server, CAFile: caFile, InsecureSkipTLSVerify: insecure, Embed: embed,
+		})
+		if err != nil {
+			return err
+		}
+
+		return addEntries(cmd, "cluster", args[0], internal.Kubeconfig{
+			Clusters: []internal.ClusterEntry{{Name: args[0], Cluster: cluster}},
+		})
+	},
+}
+
+// addUserCmd represents command to add user
+var addUserCmd = &cobra.Command{
+	Use:   "user <name>",
+	Short: "adds user to kubeconfig",
+	Long: `Adds user authenticating with --token, --token-file, --client-cert with
+	--client-key or exec credential plugin set by --exec-command to current
+	kubeconfig. Files are referenced by absolute paths, certificate and key
+	are inlined with --embed. Existing user is replaced only with --force
+
+	konfig add user eks --exec-command aws --exec-arg eks --exec-arg get-token \
+		--exec-arg --cluster-name --exec-arg prod --exec-env AWS_PROFILE=prod
+		  `,
+	Args: usageArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts := internal.UserOptions{}
+		var err error
+
+		opts.Token, err = cmd.Flags().GetString(internal.OptionToken)
+		if err != nil {
+			return err
+		}
+
+		opts.TokenFile, err = cmd.Flags().GetString(internal.OptionTokenFile)
+		if err != nil {
+			return err
+		}
+
+		opts.ClientCert, err = cmd.Flags().GetString(internal.OptionClientCert)
+		if err != nil {
+			return err
+		}
+
+		opts.ClientKey, err = cmd.Flags().GetString(internal.OptionClientKey)
+		if err != nil {
+			return err
+		}
+
+		opts.ExecCommand, err = cmd.Flags().GetString(internal.OptionExecCommand)
+		if err != nil {
+			return err
+		}
+
+		opts.ExecAPIVersion, err = cmd.Flags().GetString(internal.OptionExecAPIVersion)
+		if err != nil {
+			return err
+		}
+
+		opts.ExecArgs, err = cmd.Flags().GetStringArray(internal.OptionExecArg)
+		if err != nil {
+			return err
+		}
+
+		opts.ExecEnv, err = cmd.Flags().GetStringArray(internal.OptionExecEnv)
+		if err != nil {
+			return err
+		}
+
+		opts.Embed, err = cmd.Flags().GetBool(internal.OptionEmbed)
+		if err != nil {
+			return err
+		}
+
+		user, err := internal.NewUser(opts)
+		if err != nil {
+			return err
+		}
+
+		return addEntries(cmd, "user", args[0], internal.Kubeconfig{
+			Users: []internal.UserEntry{{Name: args[0], User: user}},
+		})
+	},
+}
+
+// addContextCmd represents command to add context
+var addContextCmd = &cobra.Command{
+	Use:   "context <name>",
+	Short: "adds context to kubeconfig",
+	Long: `Adds context for existing --cluster and --user with optional --namespace
+	to current kubeconfig. Existing context is replaced only with --force
+		  `,
+	Args: usageArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cluster, err := cmd.Flags().GetString(internal.OptionCluster)
+		if err != nil {
+			return err
+		}
+
+		user, err := cmd.Flags().GetString(internal.OptionUser)
+		if err != nil {
+			return err
+		}
+
+		namespace, err := cmd.Flags().GetString(internal.OptionNamespace)
+		if err != nil {
+			return err
+		}
+
+		return addEntries(cmd, "context", args[0], internal.Kubeconfig{
+			Contexts: []internal.ContextEntry{{Name: args[0], Context: internal.Context{
+				Cluster: cluster, User: user, Namespace: namespace,
+			}}},
+		})
+	},
+}
+
+// addEntries merges entries into current kubeconfig, refusing to introduce validation errors
+func addEntries(cmd *cobra.Command, section, name string, entries internal.Kubeconfig) error {
+	path, err := internal.GetKubeconfigPath(cmd)
+	if err != nil {
+		return err
+	}
+
+	force, err := cmd.Flags().GetBool(internal.OptionForce)
+	if err != nil {
+		return err
+	}
+
+	timeout, err := internal.GetLockTimeout(cmd)
+	if err != nil {
+		return err
+	}
+
+	setCurrent := false
+	if cmd.Flags().Lookup(internal.OptionSetCurrent) != nil {
+		setCurrent, err = cmd.Flags().GetBool(internal.OptionSetCurrent)
+		if err != nil {
+			return err
+		}
+	}
+
+	dir := filepath.Dir(path)
+
+	err = internal.UpdateOrCreateConf(path, timeout, func(config internal.Kubeconfig) (internal.Kubeconfig, error) {
+		known := internal.Validate(config, dir)
+
+		result, err := internal.MergeEntries(config, entries, force)
+		if err != nil {
+			return config, err
+		}
+
+		if setCurrent {
+			result.CurrentContext = name
+		}
+
+		findings := internal.NewErrors(known, internal.Validate(result, dir))
+		if len(findings) > 0 {
+			internal.PrintFindings(findings)
+			return config, internal.WithExitCode(internal.ExitValidation, internal.ErrFindings)
+		}
+
+		return result, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s %s added to %s\n", section, name, path)
+	return nil
+}
+
+func init() {
+	addClusterCmd.Flags().String(internal.OptionServer, "", "URL of API server")
+	addClusterCmd.Flags().String(internal.OptionCAFile, "", "CA certificate file to verify server with")
+	addClusterCmd.Flags().Bool(internal.OptionInsecure, false, "do not verify server certificate")
+	addClusterCmd.Flags().Bool(internal.OptionEmbed, false, "inline CA certificate instead of referencing file")
+	addClusterCmd.Flags().Bool(internal.OptionForce, false, "replace existing cluster")
+	_ = addClusterCmd.MarkFlagRequired(internal.OptionServer)
+
+	addUserCmd.Flags().String(internal.OptionToken, "", "bearer token")
+	addUserCmd.Flags().String(internal.OptionTokenFile, "", "file with bearer token")
+	addUserCmd.Flags().String(internal.OptionClientCert, "", "client certificate file")
+	addUserCmd.Flags().String(internal.OptionClientKey, "", "client key file")
+	addUserCmd.Flags().String(internal.OptionExecCommand, "", "exec credential plugin command")
+	addUserCmd.Flags().StringArray(internal.OptionExecArg, nil, "exec credential plugin argument, can be repeated")
+	addUserCmd.Flags().StringArray(internal.OptionExecEnv, nil, "exec credential plugin env in NAME=VALUE form, can be repeated")
+	addUserCmd.Flags().String(internal.OptionExecAPIVersion, internal.DefaultExecAPIVersion, "exec credential plugin apiVersion")
+	addUserCmd.Flags().Bool(internal.OptionEmbed, false, "inline client certificate and key instead of referencing files")
+	addUserCmd.Flags().Bool(internal.OptionForce, false, "replace existing user")
+
+	addContextCmd.Flags().String(internal.OptionCluster, "", "cluster of context")
+	addContextCmd.Flags().String(internal.OptionUser, "", "user of context")
+	addContextCmd.Flags().String(internal.OptionNamespace, "", "namespace of context")
+	addContextCmd.Flags().Bool(internal.OptionForce, false, "replace existing context")
+	addContextCmd.Flags().Bool(internal.OptionSetCurrent, false, "make context current")
+	_ = addContextCmd.MarkFlagRequired(internal.OptionCluster)
+
+	addCmd.AddCommand(addClusterCmd, addUserCmd, addContextCmd)
+	rootCmd.AddCommand(addCmd)
+}
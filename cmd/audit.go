@@ -0,0 +1,94 @@
+/*
+Copyright © 2022 ansavin
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/ansavin/konfig/internal"
+)
+
+// auditCmd represents command to look for risky kubeconfig settings
+var auditCmd = &cobra.Command{
+	Use:   "audit [</path/to/config/file>]",
+	Short: "looks for risky settings in kubeconfig",
+	Long: `Checks current kubeconfig or one stored at provided path for risky settings:
+	disabled TLS verification, plain http servers, static tokens, basic-auth
+	passwords, inline client keys, group or world readable kubeconfig and key
+	files and exec plugins started by relative path.
+
+	Every finding has rule id which can be passed to --ignore.
+	Exits with code 7 if anything is found
+		  `,
+	Args: usageArgs(cobra.MaximumNArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := internal.GetOutputFormat(cmd, internal.FormatText, internal.FormatJSON, internal.FormatSARIF)
+		if err != nil {
+			return err
+		}
+
+		ignore, err := cmd.Flags().GetStringSlice(internal.OptionIgnore)
+		if err != nil {
+			return err
+		}
+
+		path, err := internal.GetKubeconfigPath(cmd)
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 1 {
+			path = args[0]
+		}
+
+		config, err := internal.ReadConf(path)
+		if err != nil {
+			return err
+		}
+
+		findings := internal.Audit(config, path, ignore)
+
+		switch format {
+		case internal.FormatJSON:
+			err = internal.PrintJSON(findings)
+		case internal.FormatSARIF:
+			err = internal.PrintJSON(internal.NewSARIFLog(findings, path))
+		default:
+			internal.PrintFindings(findings)
+		}
+		if err != nil {
+			return err
+		}
+
+		if len(findings) > 0 {
+			return internal.ErrFindings
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	auditCmd.Flags().StringP(internal.OptionOutput, "o", internal.FormatText, "output format: text, json or sarif")
+	auditCmd.Flags().StringSlice(internal.OptionIgnore, nil, "comma separated list of rule ids to ignore, e.g. KA003,KA005")
+	rootCmd.AddCommand(auditCmd)
+}
@@ -22,36 +22,110 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
 	"github.com/spf13/cobra"
 
 	"github.com/ansavin/konfig/internal"
 )
 
-// showCmd represents command to merge kubeconfigs
+// backupCmd represents command to backup kubeconfig
 var backupCmd = &cobra.Command{
 	Use:   "backup",
 	Short: "backups current kubeconfig",
-	Long: `copys current kubeconfig to $HOME/.konfig/...
+	Long: `stores current kubeconfig as a snapshot in $HOME/.konfig/...
+	Snapshots are content-addressed, so identical kubeconfig states are stored once.
+	With --if-changed, nothing is done when kubeconfig is the same as latest snapshot of it
+
+	With --bundle, stores kubeconfig together with every certificate, key
+	and token file it references into a portable tar.gz archive
 		  `,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		kubeconfig, err := internal.GetKubeconfigPath(cmd)
 		if err != nil {
-			panic(err)
+			return err
+		}
+
+		bundle, err := cmd.Flags().GetString(internal.OptionBundle)
+		if err != nil {
+			return err
+		}
+
+		if bundle != "" {
+			err = internal.CreateBundle(kubeconfig, bundle)
+			if err != nil {
+				return err
+			}
+			return nil
+		}
+
+		store := internal.DefaultSnapshotStore()
+
+		list, err := cmd.Flags().GetBool(internal.OptionList)
+		if err != nil {
+			return err
+		}
+
+		if list {
+			snapshots, err := store.List()
+			if err != nil {
+				return err
+			}
+
+			for _, snapshot := range snapshots {
+				fmt.Printf("%s  %s  %s\n", snapshot.ShortID(), snapshot.Created.Local().Format(time.RFC3339), snapshot.Source)
+			}
+			return nil
 		}
 
 		backup, err := internal.GetBackupFilePath(cmd)
 		if err != nil {
-			panic(err)
+			return err
 		}
 
-		err = internal.CopyFileContent(kubeconfig, backup)
+		if backup != "" {
+			err = internal.CopyFileContent(kubeconfig, backup)
+			if err != nil {
+				return err
+			}
+			return nil
+		}
+
+		ifChanged, err := cmd.Flags().GetBool(internal.OptionIfChanged)
+		if err != nil {
+			return err
+		}
+
+		raw, err := os.ReadFile(kubeconfig)
+		if err != nil {
+			return err
+		}
+
+		source, err := filepath.Abs(kubeconfig)
+		if err != nil {
+			return err
+		}
+
+		snapshot, saved, err := store.Save(raw, source, ifChanged)
 		if err != nil {
-			panic(err)
+			return err
 		}
+
+		if saved {
+			fmt.Printf("snapshot %s saved\n", snapshot.ShortID())
+		}
+
+		return nil
 	},
 }
 
 func init() {
 	backupCmd.Flags().String(internal.OptionBackup, "", "specify a custom backup file")
+	backupCmd.Flags().Bool(internal.OptionIfChanged, false, "do nothing if kubeconfig is the same as latest snapshot taken from it")
+	backupCmd.Flags().Bool(internal.OptionList, false, "list stored snapshots, newest first")
+	backupCmd.Flags().String(internal.OptionBundle, "", "create a portable tar.gz bundle with kubeconfig and referenced files")
 	rootCmd.AddCommand(backupCmd)
 }
@@ -0,0 +1,106 @@
+/*
+Copyright © 2022 ansavin
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ansavin/konfig/internal"
+)
+
+// certsCmd represents command to inspect kubeconfig certificates
+var certsCmd = &cobra.Command{
+	Use:   "certs [</path/to/config/file>]",
+	Short: "shows certificates stored in or referenced by kubeconfig",
+	Long: `Decodes every certificate authority and client certificate of current kubeconfig
+	or one stored at provided path and shows subject, issuer, SANs, groups,
+	fingerprint and expiry date.
+
+	With --expiring-within, e.g. --expiring-within 30d, shows only certificates
+	which are expired, expire within given period or cannot be read and exits
+	with code 7 if any
+		  `,
+	Args: usageArgs(cobra.MaximumNArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := internal.GetOutputFormat(cmd, internal.FormatText, internal.FormatJSON)
+		if err != nil {
+			return err
+		}
+
+		within, err := cmd.Flags().GetString(internal.OptionExpiringWithin)
+		if err != nil {
+			return err
+		}
+
+		var warn time.Duration
+		if within != "" {
+			warn, err = internal.ParseDuration(within)
+			if err != nil {
+				return internal.WithExitCode(internal.ExitUsage, err)
+			}
+		}
+
+		path, err := internal.GetKubeconfigPath(cmd)
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 1 {
+			path = args[0]
+		}
+
+		config, err := internal.ReadConf(path)
+		if err != nil {
+			return err
+		}
+
+		infos := internal.InspectCertificates(config, filepath.Dir(path))
+
+		if within != "" {
+			infos = internal.ExpiringCertificates(infos, warn)
+		}
+
+		if format == internal.FormatJSON {
+			err = internal.PrintJSON(infos)
+			if err != nil {
+				return err
+			}
+		} else {
+			internal.PrintCertificates(infos, warn)
+		}
+
+		if within != "" && len(infos) > 0 {
+			return internal.ErrFindings
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	certsCmd.Flags().StringP(internal.OptionOutput, "o", internal.FormatText, "output format: text or json")
+	certsCmd.Flags().String(internal.OptionExpiringWithin, "", "show only certificates expiring within given period, e.g. 30d or 12h")
+	rootCmd.AddCommand(certsCmd)
+}
@@ -0,0 +1,107 @@
+/*
+Copyright © 2022 ansavin
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ansavin/konfig/internal"
+)
+
+// cloneCmd represents command to copy context with overrides
+var cloneCmd = &cobra.Command{
+	Use:   "clone <source context> <new context>",
+	Short: "creates copy of context with different namespace or user",
+	Long: `Creates new context for the same cluster as source context, optionally
+	with other --namespace or --user.
+
+	With --as or --as-group, user of context is duplicated under name of new
+	context with impersonation set, e.g. to get read-only access to production:
+
+	konfig clone prod prod-readonly --as system:serviceaccount:default:viewer
+
+	--as-group without --as keeps user impersonated by source user, so it
+	requires source user to impersonate someone
+		  `,
+	Args: usageArgs(cobra.ExactArgs(2)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := internal.GetKubeconfigPath(cmd)
+		if err != nil {
+			return err
+		}
+
+		namespace, err := cmd.Flags().GetString(internal.OptionNamespace)
+		if err != nil {
+			return err
+		}
+
+		user, err := cmd.Flags().GetString(internal.OptionUser)
+		if err != nil {
+			return err
+		}
+
+		as, err := cmd.Flags().GetString(internal.OptionAs)
+		if err != nil {
+			return err
+		}
+
+		asGroups, err := cmd.Flags().GetStringArray(internal.OptionAsGroup)
+		if err != nil {
+			return err
+		}
+
+		setCurrent, err := cmd.Flags().GetBool(internal.OptionSetCurrent)
+		if err != nil {
+			return err
+		}
+
+		timeout, err := internal.GetLockTimeout(cmd)
+		if err != nil {
+			return err
+		}
+
+		opts := internal.CloneOptions{
+			Namespace: namespace, User: user, As: as, AsGroups: asGroups, SetCurrent: setCurrent,
+		}
+
+		err = internal.UpdateRawConf(path, timeout, func(raw []byte) ([]byte, error) {
+			return internal.CloneConf(raw, args[0], args[1], opts)
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("context %s created\n", args[1])
+		return nil
+	},
+}
+
+func init() {
+	cloneCmd.Flags().String(internal.OptionNamespace, "", "namespace of new context")
+	cloneCmd.Flags().String(internal.OptionUser, "", "user of new context")
+	cloneCmd.Flags().String(internal.OptionAs, "", "user to impersonate")
+	cloneCmd.Flags().StringArray(internal.OptionAsGroup, nil, "group to impersonate, can be repeated")
+	cloneCmd.Flags().Bool(internal.OptionSetCurrent, false, "make new context current")
+	rootCmd.AddCommand(cloneCmd)
+}
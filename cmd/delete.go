@@ -0,0 +1,109 @@
+/*
+Copyright © 2022 ansavin
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ansavin/konfig/internal"
+)
+
+// deleteCmd groups commands removing entries from kubeconfig
+var deleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "deletes entries from kubeconfig",
+	Args:  usageArgs(cobra.NoArgs),
+}
+
+// deleteContextCmd represents command to delete contexts
+var deleteContextCmd = &cobra.Command{
+	Use:     "context <name>...",
+	Aliases: []string{"contexts"},
+	Short:   "deletes contexts together with clusters and users no other context uses",
+	Long: `Deletes contexts matching given names from current kubeconfig. Names are
+	globs, e.g. 'arn:aws:eks:*', or regular expressions with --regex. Both match
+	whole names only, so --regex prod does not delete preprod-eu, use 'prod.*' for that.
+
+	Clusters and users which are no longer used by any context are deleted too,
+	unless --keep-orphans is set. If current context is deleted, current-context
+	is cleared or switched to context set by --current.
+
+	Changes are shown and confirmed before writing, use --yes to skip
+	confirmation or --dry-run to only see them
+		  `,
+	Args: usageArgs(cobra.MinimumNArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := internal.GetKubeconfigPath(cmd)
+		if err != nil {
+			return err
+		}
+
+		regex, err := cmd.Flags().GetBool(internal.OptionRegex)
+		if err != nil {
+			return err
+		}
+
+		keepOrphans, err := cmd.Flags().GetBool(internal.OptionKeepOrphans)
+		if err != nil {
+			return err
+		}
+
+		current, err := cmd.Flags().GetString(internal.OptionCurrent)
+		if err != nil {
+			return err
+		}
+
+		return internal.ApplyChanges(cmd, path, func(config internal.Kubeconfig) (internal.Kubeconfig, error) {
+			names, err := internal.MatchNames(internal.ContextNames(config), args, regex)
+			if err != nil {
+				return config, err
+			}
+
+			previous := config.CurrentContext
+			config = internal.RemoveContexts(config, names, keepOrphans)
+
+			if current != "" && previous != config.CurrentContext {
+				if _, ok := internal.FindContext(config, current); !ok {
+					return config, internal.WithExitCode(internal.ExitNotFound, fmt.Errorf(
+						"context %q to make current not found", current,
+					))
+				}
+
+				config.CurrentContext = current
+			}
+
+			return config, nil
+		})
+	},
+}
+
+func init() {
+	deleteContextCmd.Flags().Bool(internal.OptionRegex, false, "treat names as regular expressions")
+	deleteContextCmd.Flags().Bool(internal.OptionKeepOrphans, false, "keep clusters and users no context uses")
+	deleteContextCmd.Flags().String(internal.OptionCurrent, "", "context to make current if current one is deleted")
+	deleteContextCmd.Flags().BoolP(internal.OptionYes, "y", false, "delete without confirmation")
+	deleteContextCmd.Flags().Bool(internal.OptionDryRun, false, "only show what would be deleted")
+	deleteCmd.AddCommand(deleteContextCmd)
+	rootCmd.AddCommand(deleteCmd)
+}
@@ -0,0 +1,132 @@
+/*
+Copyright © 2022 ansavin
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ansavin/konfig/internal"
+)
+
+// diffCmd represents command to compare kubeconfigs
+var diffCmd = &cobra.Command{
+	Use:   "diff [<old config>] [<new config>]",
+	Short: "shows semantic difference between two kubeconfigs",
+	Long: `Compares clusters, contexts and users of two kubeconfigs by name
+	and reports added, removed and modified entries field by field.
+	Certificates and tokens are shown as fingerprints.
+
+	konfig diff a.yaml b.yaml       compares a.yaml with b.yaml
+	konfig diff b.yaml              compares current kubeconfig with b.yaml
+	konfig diff --backup <id>       compares backup with current kubeconfig
+	konfig diff --backup <id> b.yaml compares backup with b.yaml
+
+	Exits with code 7 if kubeconfigs differ
+		  `,
+	Args: usageArgs(cobra.RangeArgs(0, 2)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := internal.GetOutputFormat(cmd, internal.FormatText, internal.FormatJSON)
+		if err != nil {
+			return err
+		}
+
+		backup, err := internal.GetBackupFilePath(cmd)
+		if err != nil {
+			return err
+		}
+
+		path, err := internal.GetKubeconfigPath(cmd)
+		if err != nil {
+			return err
+		}
+
+		var before, after internal.Kubeconfig
+
+		switch {
+		case backup != "" && len(args) == 2:
+			return internal.WithExitCode(internal.ExitUsage, errTooManyConfigs)
+		case backup != "":
+			before, err = internal.ReadBackupConf(backup)
+			if err != nil {
+				return err
+			}
+
+			if len(args) == 1 {
+				path = args[0]
+			}
+		case len(args) == 2:
+			before, err = internal.ReadConf(args[0])
+			if err != nil {
+				return err
+			}
+
+			path = args[1]
+		case len(args) == 1:
+			before, err = internal.ReadConf(path)
+			if err != nil {
+				return err
+			}
+
+			path = args[0]
+		default:
+			return internal.WithExitCode(internal.ExitUsage, errNothingToCompare)
+		}
+
+		after, err = internal.ReadConf(path)
+		if err != nil {
+			return err
+		}
+
+		changes, err := internal.Diff(before, after)
+		if err != nil {
+			return err
+		}
+
+		if format == internal.FormatJSON {
+			err = internal.PrintJSON(changes)
+			if err != nil {
+				return err
+			}
+		} else {
+			internal.PrintDiff(changes)
+		}
+
+		if len(changes) > 0 {
+			return internal.ErrFindings
+		}
+
+		return nil
+	},
+}
+
+var (
+	errTooManyConfigs   = errors.New("cannot compare backup with two kubeconfigs")
+	errNothingToCompare = errors.New("specify kubeconfig or --backup to compare with")
+)
+
+func init() {
+	diffCmd.Flags().String(internal.OptionBackup, "", "compare with backup file or snapshot id, see 'konfig backup --list'")
+	diffCmd.Flags().StringP(internal.OptionOutput, "o", internal.FormatText, "output format: text or json")
+	rootCmd.AddCommand(diffCmd)
+}
@@ -0,0 +1,77 @@
+/*
+Copyright © 2022 ansavin
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ansavin/konfig/internal"
+)
+
+// editCmd represents command to edit kubeconfig in editor
+var editCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "edits kubeconfig in editor with validation",
+	Long: `Opens copy of current kubeconfig, or only context set by --context with
+	its cluster and user, in $KUBE_EDITOR or $EDITOR. After editor is closed,
+	result is parsed and validated. If it has errors, editor is reopened with
+	errors on top, saving empty file cancels editing. Kubeconfig is written
+	atomically only if result is valid
+		  `,
+	Args: usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := internal.GetKubeconfigPath(cmd)
+		if err != nil {
+			return err
+		}
+
+		context, err := cmd.Flags().GetString(internal.OptionContext)
+		if err != nil {
+			return err
+		}
+
+		timeout, err := internal.GetLockTimeout(cmd)
+		if err != nil {
+			return err
+		}
+
+		changed, err := internal.EditConf(path, context, timeout, internal.SystemEditor)
+		if err != nil {
+			return err
+		}
+
+		if !changed {
+			fmt.Println("edit cancelled, no changes made")
+			return nil
+		}
+
+		fmt.Printf("%s edited\n", path)
+		return nil
+	},
+}
+
+func init() {
+	editCmd.Flags().String(internal.OptionContext, "", "edit only this context with its cluster and user")
+	rootCmd.AddCommand(editCmd)
+}
@@ -0,0 +1,115 @@
+/*
+Copyright © 2022 ansavin
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ansavin/konfig/internal"
+)
+
+// extractCmd represents command to get standalone kubeconfig for single context
+var extractCmd = &cobra.Command{
+	Use:   "extract <context>",
+	Short: "outputs standalone kubeconfig for single context",
+	Long: `Outputs kubeconfig which contains exactly given context together with
+	its cluster and user, with current-context set to it.
+
+	With --flatten, referenced certificate, key and token files are inlined.
+	With --redact, certificate data, keys, tokens and passwords are hidden,
+	so result can be safely shared. --namespace overrides namespace of context.
+	Result is printed to console or stored to --output file
+		  `,
+	Args: usageArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := internal.GetKubeconfigPath(cmd)
+		if err != nil {
+			return err
+		}
+
+		flatten, err := cmd.Flags().GetBool(internal.OptionFlatten)
+		if err != nil {
+			return err
+		}
+
+		redact, err := cmd.Flags().GetBool(internal.OptionRedact)
+		if err != nil {
+			return err
+		}
+
+		namespace, err := cmd.Flags().GetString(internal.OptionNamespace)
+		if err != nil {
+			return err
+		}
+
+		output, err := cmd.Flags().GetString(internal.OptionOutput)
+		if err != nil {
+			return err
+		}
+
+		timeout, err := internal.GetLockTimeout(cmd)
+		if err != nil {
+			return err
+		}
+
+		config, err := internal.ReadConf(path)
+		if err != nil {
+			return err
+		}
+
+		config, err = internal.Minify(config, args[0])
+		if err != nil {
+			return err
+		}
+
+		if namespace != "" {
+			config.Contexts[0].Context.Namespace = namespace
+		}
+
+		if flatten {
+			config, err = internal.Flatten(config, filepath.Dir(path))
+			if err != nil {
+				return err
+			}
+		}
+
+		if redact {
+			config = internal.Redact(config)
+		}
+
+		if output == "" {
+			return internal.PrintConf(config)
+		}
+
+		return internal.SaveConf(output, timeout, config)
+	},
+}
+
+func init() {
+	extractCmd.Flags().Bool(internal.OptionFlatten, false, "inline referenced certificate, key and token files")
+	extractCmd.Flags().Bool(internal.OptionRedact, false, "hide certificate data, keys, tokens and passwords")
+	extractCmd.Flags().String(internal.OptionNamespace, "", "override namespace of context")
+	extractCmd.Flags().StringP(internal.OptionOutput, "o", "", "store result to file instead of printing it")
+	rootCmd.AddCommand(extractCmd)
+}
@@ -0,0 +1,95 @@
+/*
+Copyright © 2022 ansavin
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ansavin/konfig/internal"
+)
+
+// extractCertsCmd represents command to move inline credentials to files
+var extractCertsCmd = &cobra.Command{
+	Use:   "extract-certs",
+	Short: "moves inline certificates and keys of kubeconfig to separate files",
+	Long: `Writes every inline certificate authority, client certificate and client key
+	of current kubeconfig to a file in --dir (certs folder next to kubeconfig by
+	default), readable by owner only, and replaces data fields with paths to them.
+	Existing files are kept and free names are picked, unless --force is set.
+	Kubeconfig is updated only if it still loads identically, otherwise created
+	files are removed and replaced ones get their previous content back
+		  `,
+	Args: usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := internal.GetKubeconfigPath(cmd)
+		if err != nil {
+			return err
+		}
+
+		dir, err := cmd.Flags().GetString(internal.OptionDir)
+		if err != nil {
+			return err
+		}
+
+		if dir == "" {
+			dir = filepath.Join(filepath.Dir(path), "certs")
+		}
+
+		force, err := cmd.Flags().GetBool(internal.OptionForce)
+		if err != nil {
+			return err
+		}
+
+		timeout, err := internal.GetLockTimeout(cmd)
+		if err != nil {
+			return err
+		}
+
+		var files *internal.FileChanges
+		err = internal.UpdateConf(path, path, timeout, func(config internal.Kubeconfig) (internal.Kubeconfig, error) {
+			var unflattenErr error
+			config, files, unflattenErr = internal.Unflatten(config, filepath.Dir(path), dir, force)
+			return config, unflattenErr
+		})
+		if err != nil {
+			if files != nil {
+				files.Rollback()
+			}
+			return err
+		}
+
+		for _, file := range files.Paths() {
+			fmt.Println(file)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	extractCertsCmd.Flags().String(internal.OptionDir, "", "folder to store certificates and keys to, defaults to certs folder next to kubeconfig")
+	extractCertsCmd.Flags().Bool(internal.OptionForce, false, "overwrite existing files instead of picking free names")
+	rootCmd.AddCommand(extractCertsCmd)
+}
@@ -0,0 +1,101 @@
+/*
+Copyright © 2022 ansavin
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ansavin/konfig/internal"
+)
+
+// flattenCmd represents command to make kubeconfig self-contained
+var flattenCmd = &cobra.Command{
+	Use:   "flatten",
+	Short: "makes kubeconfig self-contained by inlining referenced files",
+	Long: `Inlines content of every certificate, key and token file referenced by
+	current kubeconfig into corresponding data fields and drops path fields.
+	Relative paths are resolved against folder of kubeconfig.
+
+	With --minify, keeps only current context or one set by --context together
+	with its cluster and user, like 'kubectl config view --flatten --minify'.
+	Result is printed to console or stored to --output file
+		  `,
+	Args: usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := internal.GetKubeconfigPath(cmd)
+		if err != nil {
+			return err
+		}
+
+		minify, err := cmd.Flags().GetBool(internal.OptionMinify)
+		if err != nil {
+			return err
+		}
+
+		context, err := cmd.Flags().GetString(internal.OptionContext)
+		if err != nil {
+			return err
+		}
+
+		output, err := cmd.Flags().GetString(internal.OptionOutput)
+		if err != nil {
+			return err
+		}
+
+		timeout, err := internal.GetLockTimeout(cmd)
+		if err != nil {
+			return err
+		}
+
+		config, err := internal.ReadConf(path)
+		if err != nil {
+			return err
+		}
+
+		if minify || context != "" {
+			config, err = internal.Minify(config, context)
+			if err != nil {
+				return err
+			}
+		}
+
+		config, err = internal.Flatten(config, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+
+		if output == "" {
+			return internal.PrintConf(config)
+		}
+
+		return internal.SaveConf(output, timeout, config)
+	},
+}
+
+func init() {
+	flattenCmd.Flags().Bool(internal.OptionMinify, false, "keep only current context with its cluster and user")
+	flattenCmd.Flags().String(internal.OptionContext, "", "context to keep with --minify instead of current one")
+	flattenCmd.Flags().StringP(internal.OptionOutput, "o", "", "store result to file instead of printing it")
+	rootCmd.AddCommand(flattenCmd)
+}
@@ -0,0 +1,97 @@
+/*
+Copyright © 2022 ansavin
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/ansavin/konfig/internal"
+)
+
+// fromInClusterCmd represents command to build kubeconfig from pod service account
+var fromInClusterCmd = &cobra.Command{
+	Use:   "from-incluster",
+	Short: "builds kubeconfig from service account mounted into pod",
+	Long: `Creates cluster, user and context for accessing API server from inside
+	pod, like client-go in-cluster config does. Server is taken from
+	KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT, credentials from
+	/var/run/secrets/kubernetes.io/serviceaccount under --root.
+
+	Token is referenced by tokenFile, so rotated tokens keep working.
+	Entries are merged into current kubeconfig, existing ones are replaced only
+	with --force. With --output, standalone kubeconfig is written to file
+	instead, or printed if it is -
+		  `,
+	Args: usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := internal.GetKubeconfigPath(cmd)
+		if err != nil {
+			return err
+		}
+
+		root, err := cmd.Flags().GetString(internal.OptionRoot)
+		if err != nil {
+			return err
+		}
+
+		name, err := cmd.Flags().GetString(internal.OptionName)
+		if err != nil {
+			return err
+		}
+
+		output, err := cmd.Flags().GetString(internal.OptionOutput)
+		if err != nil {
+			return err
+		}
+
+		force, err := cmd.Flags().GetBool(internal.OptionForce)
+		if err != nil {
+			return err
+		}
+
+		setCurrent, err := cmd.Flags().GetBool(internal.OptionSetCurrent)
+		if err != nil {
+			return err
+		}
+
+		timeout, err := internal.GetLockTimeout(cmd)
+		if err != nil {
+			return err
+		}
+
+		config, err := internal.InClusterConf(root, name)
+		if err != nil {
+			return err
+		}
+
+		return saveGenerated(path, output, timeout, config, force, setCurrent)
+	},
+}
+
+func init() {
+	fromInClusterCmd.Flags().String(internal.OptionRoot, "/", "folder to look for service account mount in")
+	fromInClusterCmd.Flags().String(internal.OptionName, internal.DefaultInClusterName, "name of created entries")
+	fromInClusterCmd.Flags().StringP(internal.OptionOutput, "o", "", "write standalone kubeconfig to file, - to print it")
+	fromInClusterCmd.Flags().Bool(internal.OptionForce, false, "replace existing entries with the same name")
+	fromInClusterCmd.Flags().Bool(internal.OptionSetCurrent, false, "make created context current")
+	rootCmd.AddCommand(fromInClusterCmd)
+}
@@ -0,0 +1,154 @@
+/*
+Copyright © 2022 ansavin
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ansavin/konfig/internal"
+)
+
+// importSACmd represents command to build kubeconfig from service account token Secret
+var importSACmd = &cobra.Command{
+	Use:   "import-sa <secret.yaml|->",
+	Short: "builds kubeconfig from service account token Secret manifest",
+	Long: `Reads Secret of type kubernetes.io/service-account-token from manifest
+	file or stdin, e.g. 'kubectl get secret ci-token -o yaml | konfig import-sa - --server ...',
+	and creates cluster, user and context named after service account with its
+	token, CA certificate and namespace.
+
+	Entries are merged into current kubeconfig, existing ones are replaced only
+	with --force. With --output, standalone kubeconfig is written to file
+	instead, or printed if it is -
+		  `,
+	Args: usageArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := internal.GetKubeconfigPath(cmd)
+		if err != nil {
+			return err
+		}
+
+		server, err := cmd.Flags().GetString(internal.OptionServer)
+		if err != nil {
+			return err
+		}
+
+		name, err := cmd.Flags().GetString(internal.OptionName)
+		if err != nil {
+			return err
+		}
+
+		output, err := cmd.Flags().GetString(internal.OptionOutput)
+		if err != nil {
+			return err
+		}
+
+		force, err := cmd.Flags().GetBool(internal.OptionForce)
+		if err != nil {
+			return err
+		}
+
+		setCurrent, err := cmd.Flags().GetBool(internal.OptionSetCurrent)
+		if err != nil {
+			return err
+		}
+
+		timeout, err := internal.GetLockTimeout(cmd)
+		if err != nil {
+			return err
+		}
+
+		var raw []byte
+		if args[0] == "-" {
+			raw, err = io.ReadAll(cmd.InOrStdin())
+		} else {
+			raw, err = os.ReadFile(args[0])
+		}
+		if err != nil {
+			return err
+		}
+
+		creds, err := internal.ParseServiceAccountSecret(raw)
+		if err != nil {
+			return err
+		}
+
+		if name == "" {
+			name = creds.Name
+		}
+
+		config := internal.ServiceAccountConf(creds, name, server)
+		return saveGenerated(path, output, timeout, config, force, setCurrent)
+	},
+}
+
+// saveGenerated validates generated single context kubeconfig and stores it to output,
+// prints it if output is -, or merges it into kubeconfig at path if output is empty
+func saveGenerated(path, output string, timeout time.Duration, config internal.Kubeconfig, force, setCurrent bool) error {
+	findings := internal.Validate(config, "")
+	if internal.HasErrors(findings) {
+		internal.PrintFindings(findings)
+		return internal.WithExitCode(internal.ExitValidation, internal.ErrFindings)
+	}
+
+	switch output {
+	case "-":
+		return internal.PrintConf(config)
+	case "":
+	default:
+		return internal.SaveConf(output, timeout, config)
+	}
+
+	err := internal.UpdateOrCreateConf(path, timeout, func(current internal.Kubeconfig) (internal.Kubeconfig, error) {
+		current, err := internal.MergeEntries(current, config, force)
+		if err != nil {
+			return current, err
+		}
+
+		if setCurrent {
+			current.CurrentContext = config.CurrentContext
+		}
+
+		return current, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("context %s added to %s\n", config.CurrentContext, path)
+	return nil
+}
+
+func init() {
+	importSACmd.Flags().String(internal.OptionServer, "", "URL of API server")
+	importSACmd.Flags().String(internal.OptionName, "", "name of created entries instead of service account name")
+	importSACmd.Flags().StringP(internal.OptionOutput, "o", "", "write standalone kubeconfig to file, - to print it")
+	importSACmd.Flags().Bool(internal.OptionForce, false, "replace existing entries with the same name")
+	importSACmd.Flags().Bool(internal.OptionSetCurrent, false, "make imported context current")
+	_ = importSACmd.MarkFlagRequired(internal.OptionServer)
+	rootCmd.AddCommand(importSACmd)
+}
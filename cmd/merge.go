@@ -22,11 +22,7 @@ THE SOFTWARE.
 package cmd
 
 import (
-	"fmt"
-	"os"
-
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v2"
 
 	"github.com/ansavin/konfig/internal"
 )
@@ -38,40 +34,31 @@ var mergeCmd = &cobra.Command{
 	Long: `Merges config from provided path with currently selected one.
 	After execution currently selected config is modified and needs manual save.
 		  `,
-	Args: cobra.MinimumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	Args: usageArgs(cobra.MinimumNArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
 		path, err := internal.GetKubeconfigPath(cmd)
 		if err != nil {
-			panic(err)
+			return err
 		}
 
 		output, err := internal.GetOutputFilePath(cmd)
 		if err != nil {
-			panic(err)
+			return err
 		}
 
-		currentConfig, err := internal.ReadConf(path)
+		timeout, err := internal.GetLockTimeout(cmd)
 		if err != nil {
-			panic(err)
+			return err
 		}
+
 		extraConf, err := internal.ReadConf(args[0])
 		if err != nil {
-			panic(err)
-		}
-		currentConfig, err = internal.Merge(currentConfig, extraConf)
-		if err != nil {
-			// no need to exit here - just print error and no nothing
-			fmt.Println(err)
-			return
-		}
-		raw, err := yaml.Marshal(currentConfig)
-		if err != nil {
-			panic(err)
-		}
-		err = os.WriteFile(output, raw, os.FileMode(0600))
-		if err != nil {
-			panic(err)
+			return err
 		}
+
+		return internal.UpdateConf(path, output, timeout, func(currentConfig internal.Kubeconfig) (internal.Kubeconfig, error) {
+			return internal.Merge(currentConfig, extraConf)
+		})
 	},
 }
 
@@ -0,0 +1,75 @@
+/*
+Copyright © 2022 ansavin
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ansavin/konfig/internal"
+)
+
+// pruneCmd represents command to remove unused and broken entries
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "removes unused, broken and duplicate entries from kubeconfig",
+	Long: `Finds and removes entries of current kubeconfig which are of no use:
+
+	duplicate  cluster or user with the same content as another one,
+	           contexts using it are switched to that one
+	broken     context referencing missing cluster or user
+	expired    context whose user has expired client certificate
+	unused     cluster or user no remaining context uses
+
+	Changes are shown and confirmed before writing, use --yes to skip
+	confirmation or --dry-run to only see them
+		  `,
+	Args: usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := internal.GetKubeconfigPath(cmd)
+		if err != nil {
+			return err
+		}
+
+		// duplicates are compared by raw content, which keeps fields konfig does not know
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("cannot open kubeconfig: %w", err)
+		}
+
+		return internal.ApplyChanges(cmd, path, func(config internal.Kubeconfig) (internal.Kubeconfig, error) {
+			candidates := internal.FindPrunable(config, raw, filepath.Dir(path))
+			internal.PrintPruneCandidates(candidates)
+
+			return internal.Prune(config, candidates), nil
+		})
+	},
+}
+
+func init() {
+	pruneCmd.Flags().BoolP(internal.OptionYes, "y", false, "prune without confirmation")
+	pruneCmd.Flags().Bool(internal.OptionDryRun, false, "only show what would be pruned")
+	rootCmd.AddCommand(pruneCmd)
+}
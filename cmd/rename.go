@@ -0,0 +1,150 @@
+/*
+Copyright © 2022 ansavin
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ansavin/konfig/internal"
+)
+
+// renameCmd groups commands renaming kubeconfig entries
+var renameCmd = &cobra.Command{
+	Use:   "rename",
+	Short: "renames clusters, contexts and users updating references to them",
+	Args:  usageArgs(cobra.NoArgs),
+}
+
+var errRenameArgs = errors.New("specify old and new names, or --from-file")
+
+// newRenameCmd creates command to rename entries of section
+func newRenameCmd(section string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   section + " <old> <new>",
+		Short: fmt.Sprintf("renames %s updating references to it", section),
+		Long: fmt.Sprintf(`Renames %[1]s of current kubeconfig and updates every reference to it,
+	refusing to take name of another %[1]s.
+
+	konfig rename %[1]s old new                     renames single %[1]s
+	konfig rename %[1]s --regex 'arn:.*/(.*)' '$1'  renames every matching %[1]s,
+	                                                 matches are replaced like in sed
+	konfig rename %[1]s --from-file renames.yaml    renames according to yaml
+	                                                 mapping of old names to new ones
+		  `, section),
+		Args: usageArgs(cobra.MaximumNArgs(2)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := internal.GetKubeconfigPath(cmd)
+			if err != nil {
+				return err
+			}
+
+			regex, err := cmd.Flags().GetBool(internal.OptionRegex)
+			if err != nil {
+				return err
+			}
+
+			fromFile, err := cmd.Flags().GetString(internal.OptionFromFile)
+			if err != nil {
+				return err
+			}
+
+			timeout, err := internal.GetLockTimeout(cmd)
+			if err != nil {
+				return err
+			}
+
+			byArgs := fromFile == "" && len(args) == 2
+			byFile := fromFile != "" && len(args) == 0 && !regex
+			if !byArgs && !byFile {
+				return internal.WithExitCode(internal.ExitUsage, errRenameArgs)
+			}
+
+			var fileRenames map[string]string
+			if fromFile != "" {
+				fileRenames, err = internal.ReadRenames(fromFile)
+				if err != nil {
+					return err
+				}
+			}
+
+			var renames map[string]string
+
+			err = internal.UpdateRawConf(path, timeout, func(raw []byte) ([]byte, error) {
+				config, err := internal.ParseConf(raw)
+				if err != nil {
+					return nil, err
+				}
+
+				switch {
+				case fromFile != "":
+					renames = fileRenames
+				case regex:
+					names := map[string][]string{
+						"cluster": internal.ClusterNames(config),
+						"context": internal.ContextNames(config),
+						"user":    internal.UserNames(config),
+					}[section]
+
+					renames, err = internal.RegexRenames(names, args[0], args[1])
+					if err != nil {
+						return nil, err
+					}
+				default:
+					renames = map[string]string{args[0]: args[1]}
+				}
+
+				return internal.RenameConf(raw, section, renames)
+			})
+			if err != nil {
+				return err
+			}
+
+			olds := []string{}
+			for old := range renames {
+				olds = append(olds, old)
+			}
+			sort.Strings(olds)
+
+			for _, old := range olds {
+				fmt.Printf("%s %s renamed to %s\n", section, old, renames[old])
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool(internal.OptionRegex, false, "treat old name as regular expression and new one as replacement")
+	cmd.Flags().String(internal.OptionFromFile, "", "yaml file mapping old names to new ones")
+
+	return cmd
+}
+
+func init() {
+	for _, section := range []string{"cluster", "context", "user"} {
+		renameCmd.AddCommand(newRenameCmd(section))
+	}
+	rootCmd.AddCommand(renameCmd)
+}
@@ -22,36 +22,120 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"os"
+	"path/filepath"
+
 	"github.com/spf13/cobra"
 
 	"github.com/ansavin/konfig/internal"
 )
 
-// showCmd represents command to merge kubeconfigs
+// restoreCmd represents command to restore kubeconfig from backup
 var restoreCmd = &cobra.Command{
 	Use:   "restore",
 	Short: "restores current kubeconfig",
-	Long: `copies backup file content from $HOME/.konfig/... to $HOME/.kube/config
+	Long: `copies latest snapshot of kubeconfig from $HOME/.konfig/... back to it
+	Use --snapshot to pick another snapshot by id, see 'konfig backup --list'
+
+	With --bundle, unpacks referenced files from a portable bundle into --bundle-dir
+	(folder of kubeconfig by default) and points restored kubeconfig to them
 		  `,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		kubeconfig, err := internal.GetKubeconfigPath(cmd)
 		if err != nil {
-			panic(err)
+			return err
+		}
+
+		timeout, err := internal.GetLockTimeout(cmd)
+		if err != nil {
+			return err
+		}
+
+		bundle, err := cmd.Flags().GetString(internal.OptionBundle)
+		if err != nil {
+			return err
+		}
+
+		if bundle != "" {
+			dir, err := cmd.Flags().GetString(internal.OptionBundleDir)
+			if err != nil {
+				return err
+			}
+
+			if dir == "" {
+				dir = filepath.Dir(kubeconfig)
+			}
+
+			dir, err = filepath.Abs(dir)
+			if err != nil {
+				return err
+			}
+
+			raw, err := internal.ExtractBundle(bundle, dir)
+			if err != nil {
+				return err
+			}
+
+			err = internal.WithLock(kubeconfig, timeout, func() error {
+				return internal.WriteFileAtomic(kubeconfig, raw, os.FileMode(0600))
+			})
+			if err != nil {
+				return err
+			}
+			return nil
 		}
 
 		backup, err := internal.GetBackupFilePath(cmd)
 		if err != nil {
-			panic(err)
+			return err
+		}
+
+		if backup != "" {
+			err = internal.WithLock(kubeconfig, timeout, func() error {
+				return internal.CopyFileContent(backup, kubeconfig)
+			})
+			if err != nil {
+				return err
+			}
+			return nil
 		}
 
-		err = internal.CopyFileContent(backup, kubeconfig)
+		id, err := cmd.Flags().GetString(internal.OptionSnapshot)
 		if err != nil {
-			panic(err)
+			return err
 		}
+
+		store := internal.DefaultSnapshotStore()
+
+		var snapshot internal.Snapshot
+		if id == "" {
+			// snapshots of other kubeconfigs must not be restored over this one
+			source, err := filepath.Abs(kubeconfig)
+			if err != nil {
+				return err
+			}
+
+			snapshot, err = store.LatestFrom(source)
+			if err != nil {
+				return internal.WithExitCode(internal.ExitNotFound, err)
+			}
+		} else {
+			snapshot, err = store.Find(id)
+		}
+		if err != nil {
+			return err
+		}
+
+		return internal.WithLock(kubeconfig, timeout, func() error {
+			return store.Restore(snapshot, kubeconfig)
+		})
 	},
 }
 
 func init() {
 	restoreCmd.Flags().String(internal.OptionBackup, "", "specify a custom backup file")
+	restoreCmd.Flags().String(internal.OptionSnapshot, "", "restore snapshot with given id instead of latest one")
+	restoreCmd.Flags().String(internal.OptionBundle, "", "restore from a portable tar.gz bundle")
+	restoreCmd.Flags().String(internal.OptionBundleDir, "", "folder to unpack bundled files to, defaults to kubeconfig folder")
 	rootCmd.AddCommand(restoreCmd)
 }
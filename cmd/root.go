@@ -22,7 +22,10 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"strings"
 
 	"github.com/ansavin/konfig/internal"
 
@@ -36,7 +39,19 @@ var rootCmd = &cobra.Command{
 	Long: `konfig - kubectl config file manager, cli tool for choosing, 
 editing, backuping, viewing and merging of kubectl config files 
 that are usually stored in '~/.kube/' folder
+
+Exit codes:
+  0  success
+  1  unexpected failure
+  2  wrong arguments or flags
+  3  kubeconfig, backup or other requested object not found
+  4  conflict, e.g. kubeconfig is locked or entries cannot be merged
+  5  kubeconfig or other input is malformed
+  6  reading or writing files failed
+  7  differences or problems found by diff and inspection commands
 		  `,
+	SilenceErrors: true,
+	SilenceUsage:  true,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -44,7 +59,26 @@ that are usually stored in '~/.kube/' folder
 func Execute() {
 	err := rootCmd.Execute()
 	if err != nil {
-		os.Exit(1)
+		// cobra reports unknown subcommands and missing required flags with plain errors
+		if strings.HasPrefix(err.Error(), "unknown command") || strings.HasPrefix(err.Error(), "required flag") {
+			err = internal.WithExitCode(internal.ExitUsage, err)
+		}
+
+		if !errors.Is(err, internal.ErrFindings) {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		}
+		if internal.ExitCode(err) == internal.ExitUsage {
+			fmt.Fprintf(os.Stderr, "Run 'konfig --help' for usage.\n")
+		}
+
+		os.Exit(internal.ExitCode(err))
+	}
+}
+
+// usageArgs marks errors of args validator as usage errors
+func usageArgs(validate cobra.PositionalArgs) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		return internal.WithExitCode(internal.ExitUsage, validate(cmd, args))
 	}
 }
 
@@ -57,8 +91,14 @@ func init() {
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return internal.WithExitCode(internal.ExitUsage, err)
+	})
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 	rootCmd.PersistentFlags().String(
 		internal.OptionKubeconfig, "", "specify a custom kubeconfig file instead of default in ~/.kube/config",
 	)
+	rootCmd.PersistentFlags().Duration(
+		internal.OptionLockTimeout, internal.DefaultLockTimeout, "how long to wait for other programs to release kubeconfig lock",
+	)
 }
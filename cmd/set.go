@@ -0,0 +1,116 @@
+/*
+Copyright © 2022 ansavin
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ansavin/konfig/internal"
+)
+
+// setCmd represents command to set kubeconfig field
+var setCmd = &cobra.Command{
+	Use:   "set <path> <value>",
+	Short: "sets field of kubeconfig",
+	Long: `Sets field of current kubeconfig addressed by path, creating cluster,
+	context or user if it does not exist. Comments and formatting are kept.
+
+	konfig set clusters.<name>.server https://example.com:6443
+	konfig set clusters.<name>.certificate-authority-data @ca.crt
+	konfig set users.<name>.token @token
+	konfig set users.<name>.exec.args '[eks, get-token]'
+	konfig set contexts.<name>.namespace default
+	konfig set current-context <name>
+
+	Value @<file> is read from file and - from stdin, content for *-data
+	fields is base64 encoded. Lists and objects are given as yaml
+		  `,
+	Args: usageArgs(cobra.ExactArgs(2)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := internal.GetKubeconfigPath(cmd)
+		if err != nil {
+			return err
+		}
+
+		timeout, err := internal.GetLockTimeout(cmd)
+		if err != nil {
+			return err
+		}
+
+		value, err := internal.ReadValue(args[1], args[0], cmd.InOrStdin())
+		if err != nil {
+			return err
+		}
+
+		err = internal.UpdateRawConf(path, timeout, func(raw []byte) ([]byte, error) {
+			return internal.SetField(raw, args[0], value)
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s set\n", args[0])
+		return nil
+	},
+}
+
+// unsetCmd represents command to unset kubeconfig field
+var unsetCmd = &cobra.Command{
+	Use:   "unset <path>",
+	Short: "unsets field of kubeconfig",
+	Long: `Removes field of current kubeconfig addressed by path, or the whole
+	cluster, context or user if path ends with its name. Comments and formatting are kept.
+
+	konfig unset contexts.<name>.namespace
+	konfig unset users.<name>
+	konfig unset current-context
+		  `,
+	Args: usageArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := internal.GetKubeconfigPath(cmd)
+		if err != nil {
+			return err
+		}
+
+		timeout, err := internal.GetLockTimeout(cmd)
+		if err != nil {
+			return err
+		}
+
+		err = internal.UpdateRawConf(path, timeout, func(raw []byte) ([]byte, error) {
+			return internal.UnsetField(raw, args[0])
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s unset\n", args[0])
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(setCmd)
+	rootCmd.AddCommand(unsetCmd)
+}
@@ -27,23 +27,24 @@ import (
 	"github.com/ansavin/konfig/internal"
 )
 
-// showCmd represents command to merge kubeconfigs
+// showCmd represents command to show kubeconfig
 var showCmd = &cobra.Command{
 	Use:   "show",
 	Short: "shows current kubeconfig",
 	Long: `Prints current kubeconfig to console
 		  `,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		path, err := internal.GetKubeconfigPath(cmd)
 		if err != nil {
-			panic(err)
+			return err
 		}
 
 		currentConfig, err := internal.ReadConf(path)
 		if err != nil {
-			panic(err)
+			return err
 		}
-		internal.PrettyPrint(currentConfig)
+
+		return internal.PrettyPrint(currentConfig)
 	},
 }
 
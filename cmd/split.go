@@ -0,0 +1,123 @@
+/*
+Copyright © 2022 ansavin
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ansavin/konfig/internal"
+)
+
+// splitCmd represents command to split kubeconfig into one file per context
+var splitCmd = &cobra.Command{
+	Use:   "split",
+	Short: "splits kubeconfig into one self-contained file per context",
+	Long: `Writes minimal self-contained kubeconfig for every context of current kubeconfig
+	into --dir. Every file contains a context with its cluster and user, has
+	current-context set and referenced files inlined.
+
+	File names are built from --template, which may use {{.Context}}, {{.Cluster}}
+	and {{.User}}. With --delete, split contexts are removed from current kubeconfig
+	together with clusters and users no other context uses. If any file or current
+	kubeconfig cannot be written, files written by split are rolled back
+		  `,
+	Args: usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := internal.GetKubeconfigPath(cmd)
+		if err != nil {
+			return err
+		}
+
+		dir, err := cmd.Flags().GetString(internal.OptionDir)
+		if err != nil {
+			return err
+		}
+
+		nameTemplate, err := cmd.Flags().GetString(internal.OptionTemplate)
+		if err != nil {
+			return err
+		}
+
+		remove, err := cmd.Flags().GetBool(internal.OptionDelete)
+		if err != nil {
+			return err
+		}
+
+		timeout, err := internal.GetLockTimeout(cmd)
+		if err != nil {
+			return err
+		}
+
+		var written *internal.FileChanges
+		split := func(config internal.Kubeconfig) (internal.Kubeconfig, error) {
+			files, err := internal.Split(config, dir, nameTemplate)
+			if err != nil {
+				return config, err
+			}
+
+			written, err = internal.WriteSplitFiles(files, path, timeout)
+			if err != nil {
+				return config, err
+			}
+
+			names := []string{}
+			for _, file := range files {
+				names = append(names, file.Context)
+			}
+
+			return internal.RemoveContexts(config, names, false), nil
+		}
+
+		if remove {
+			err = internal.UpdateConf(path, path, timeout, split)
+		} else {
+			var config internal.Kubeconfig
+			config, err = internal.ReadConf(path)
+			if err == nil {
+				_, err = split(config)
+			}
+		}
+		if err != nil {
+			// source is left as is, so split is undone as a whole
+			if written != nil {
+				written.Rollback()
+			}
+			return err
+		}
+
+		for _, file := range written.Paths() {
+			fmt.Println(file)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	splitCmd.Flags().String(internal.OptionDir, "", "folder to store kubeconfigs to")
+	splitCmd.Flags().String(internal.OptionTemplate, internal.DefaultSplitTemplate, "template of file names")
+	splitCmd.Flags().Bool(internal.OptionDelete, false, "delete split contexts from current kubeconfig")
+	_ = splitCmd.MarkFlagRequired(internal.OptionDir)
+	rootCmd.AddCommand(splitCmd)
+}
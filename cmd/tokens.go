@@ -0,0 +1,88 @@
+/*
+Copyright © 2022 ansavin
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ansavin/konfig/internal"
+)
+
+// tokensCmd represents command to inspect bearer tokens of kubeconfig users
+var tokensCmd = &cobra.Command{
+	Use:   "tokens [</path/to/config/file>]",
+	Short: "shows details of bearer tokens of kubeconfig users",
+	Long: `Decodes JWT bearer tokens of users of current kubeconfig or one stored at
+	provided path, without verifying signatures, and shows issuer, subject, audience,
+	service account and issue and expiry times. Tokens themselves are never printed,
+	only their fingerprints.
+
+	Exits with code 7 if any token is expired
+		  `,
+	Args: usageArgs(cobra.MaximumNArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := internal.GetOutputFormat(cmd, internal.FormatText, internal.FormatJSON)
+		if err != nil {
+			return err
+		}
+
+		path, err := internal.GetKubeconfigPath(cmd)
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 1 {
+			path = args[0]
+		}
+
+		config, err := internal.ReadConf(path)
+		if err != nil {
+			return err
+		}
+
+		infos := internal.InspectTokens(config, filepath.Dir(path))
+
+		if format == internal.FormatJSON {
+			err = internal.PrintJSON(infos)
+			if err != nil {
+				return err
+			}
+		} else {
+			internal.PrintTokens(infos)
+		}
+
+		for _, info := range infos {
+			if info.Expired {
+				return internal.ErrFindings
+			}
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	tokensCmd.Flags().StringP(internal.OptionOutput, "o", internal.FormatText, "output format: text or json")
+	rootCmd.AddCommand(tokensCmd)
+}
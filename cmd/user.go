@@ -0,0 +1,264 @@
+/*
+Copyright © 2022 ansavin
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ansavin/konfig/internal"
+)
+
+// csrPollInterval is how often signing status of submitted request is checked
+const csrPollInterval = 2 * time.Second
+
+// userCmd groups commands managing users
+var userCmd = &cobra.Command{
+	Use:   "user",
+	Short: "manages kubeconfig users",
+	Args:  usageArgs(cobra.NoArgs),
+}
+
+// userNewCmd represents command to provision user with client certificate
+var userNewCmd = &cobra.Command{
+	Use:   "new <name>",
+	Short: "generates key and certificate signing request for new user",
+	Long: `Generates private key (--key-type ecdsa or rsa) and certificate signing
+	request for user name in every --group, and writes them to <name>.key and
+	<name>.csr in --dir. With --submit request is sent to API of current context
+	(or --context) as certificates.k8s.io/v1 CertificateSigningRequest named
+	<name>-<random suffix>, approved with --approve, and once signed key, request
+	and certificate (<name>.crt) are written and user is added to current kubeconfig.
+	Existing user is only replaced with --force, and it is checked before request
+	is submitted. Nothing is written if request is not signed or user is not added
+
+	konfig user new alice --group dev --submit --approve
+		  `,
+	Args: usageArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+
+		groups, err := cmd.Flags().GetStringArray(internal.OptionGroup)
+		if err != nil {
+			return err
+		}
+
+		keyType, err := cmd.Flags().GetString(internal.OptionKeyType)
+		if err != nil {
+			return err
+		}
+
+		dir, err := cmd.Flags().GetString(internal.OptionDir)
+		if err != nil {
+			return err
+		}
+
+		submit, err := cmd.Flags().GetBool(internal.OptionSubmit)
+		if err != nil {
+			return err
+		}
+
+		approve, err := cmd.Flags().GetBool(internal.OptionApprove)
+		if err != nil {
+			return err
+		}
+
+		wait, err := cmd.Flags().GetDuration(internal.OptionWait)
+		if err != nil {
+			return err
+		}
+
+		expirationFlag, err := cmd.Flags().GetString(internal.OptionExpiration)
+		if err != nil {
+			return err
+		}
+
+		context, err := cmd.Flags().GetString(internal.OptionContext)
+		if err != nil {
+			return err
+		}
+
+		embed, err := cmd.Flags().GetBool(internal.OptionEmbed)
+		if err != nil {
+			return err
+		}
+
+		force, err := cmd.Flags().GetBool(internal.OptionForce)
+		if err != nil {
+			return err
+		}
+
+		if !submit && (approve || context != "" || embed) {
+			return internal.WithExitCode(internal.ExitUsage, errors.New("--approve, --context and --embed require --submit"))
+		}
+
+		var expiration time.Duration
+		if expirationFlag != "" {
+			expiration, err = internal.ParseDuration(expirationFlag)
+			if err != nil {
+				return internal.WithExitCode(internal.ExitUsage, err)
+			}
+
+			err = internal.ValidateCSRExpiration(expiration)
+			if err != nil {
+				return err
+			}
+		}
+
+		keyPath := filepath.Join(dir, name+".key")
+		csrPath := filepath.Join(dir, name+".csr")
+		certPath := filepath.Join(dir, name+".crt")
+
+		if !force {
+			for _, file := range []string{keyPath, csrPath, certPath} {
+				if _, err := os.Stat(file); err == nil {
+					return internal.WithExitCode(internal.ExitConflict, fmt.Errorf("%s already exists, use --force to overwrite", file))
+				}
+			}
+		}
+
+		// with --submit cluster is only asked to sign request if user can be added
+		var client *internal.APIClient
+		if submit {
+			path, err := internal.GetKubeconfigPath(cmd)
+			if err != nil {
+				return err
+			}
+
+			config, err := internal.ReadConf(path)
+			if err != nil {
+				return err
+			}
+
+			if _, ok := internal.FindUser(config, name); ok && !force {
+				return internal.WithExitCode(internal.ExitConflict, fmt.Errorf("user %q already exists, use --force to replace it", name))
+			}
+
+			client, err = internal.NewAPIClient(config, context, filepath.Dir(path))
+			if err != nil {
+				return err
+			}
+		}
+
+		key, keyPEM, err := internal.GenerateKey(keyType)
+		if err != nil {
+			return err
+		}
+
+		csr, err := internal.CreateCSR(key, name, groups)
+		if err != nil {
+			return err
+		}
+
+		contents := map[string][]byte{keyPath: keyPEM, csrPath: csr}
+
+		if submit {
+			requestName, err := internal.CSRName(name)
+			if err != nil {
+				return err
+			}
+
+			contents[certPath], err = internal.RequestCertificate(client, requestName, csr, expiration, approve, wait, csrPollInterval)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = os.MkdirAll(dir, os.FileMode(0700))
+		if err != nil {
+			return err
+		}
+
+		// files are written only once certificate is signed and rolled back if user
+		// is not added, so a failed run never replaces key of existing user
+		files := &internal.FileChanges{}
+		for _, file := range []string{keyPath, csrPath, certPath} {
+			content, ok := contents[file]
+			if !ok {
+				continue
+			}
+
+			mode := os.FileMode(0644)
+			if file == keyPath {
+				mode = os.FileMode(0600)
+			}
+
+			err = files.Write(file, content, mode)
+			if err != nil {
+				files.Rollback()
+				return err
+			}
+		}
+
+		if submit {
+			err = addUser(cmd, name, certPath, keyPath, embed)
+			if err != nil {
+				files.Rollback()
+				return err
+			}
+		}
+
+		for _, file := range files.Paths() {
+			fmt.Println(file)
+		}
+
+		if !submit {
+			return nil
+		}
+
+		fmt.Printf("use it with 'konfig add context <name> --cluster <cluster> --user %s'\n", name)
+		return nil
+	},
+}
+
+// addUser adds user authenticating with client certificate and key files to kubeconfig
+func addUser(cmd *cobra.Command, name, certPath, keyPath string, embed bool) error {
+	user, err := internal.NewUser(internal.UserOptions{ClientCert: certPath, ClientKey: keyPath, Embed: embed})
+	if err != nil {
+		return err
+	}
+
+	return addEntries(cmd, "user", name, internal.Kubeconfig{
+		Users: []internal.UserEntry{{Name: name, User: user}},
+	})
+}
+
+func init() {
+	userNewCmd.Flags().StringArray(internal.OptionGroup, nil, "group user belongs to, can be repeated")
+	userNewCmd.Flags().String(internal.OptionKeyType, internal.KeyTypeECDSA, "type of private key, ecdsa or rsa")
+	userNewCmd.Flags().String(internal.OptionDir, ".", "folder to store key, request and certificate to")
+	userNewCmd.Flags().Bool(internal.OptionSubmit, false, "submit request to cluster and add signed user to kubeconfig")
+	userNewCmd.Flags().Bool(internal.OptionApprove, false, "approve submitted request, requires permission to approve")
+	userNewCmd.Flags().Duration(internal.OptionWait, 5*time.Minute, "how long to wait for request to be signed")
+	userNewCmd.Flags().String(internal.OptionExpiration, "", "requested certificate lifetime like 30d or 12h, signer default if empty")
+	userNewCmd.Flags().String(internal.OptionContext, "", "context to submit request through instead of current one")
+	userNewCmd.Flags().Bool(internal.OptionEmbed, false, "inline certificate and key instead of referencing files")
+	userNewCmd.Flags().Bool(internal.OptionForce, false, "overwrite existing files and user")
+
+	userCmd.AddCommand(userNewCmd)
+	rootCmd.AddCommand(userCmd)
+}
@@ -0,0 +1,86 @@
+/*
+Copyright © 2022 ansavin
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ansavin/konfig/internal"
+)
+
+// validateCmd represents command to validate kubeconfig
+var validateCmd = &cobra.Command{
+	Use:   "validate [</path/to/config/file>]",
+	Short: "checks kubeconfig for structural and referential problems",
+	Long: `Checks current kubeconfig or one stored at provided path for problems
+	kubectl reports only at use time: references to missing clusters, users and
+	contexts, duplicate names, malformed servers, broken base64 and PEM data,
+	missing referenced files and exec plugins not found on PATH.
+
+	Exits with code 5 if errors are found
+		  `,
+	Args: usageArgs(cobra.MaximumNArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := internal.GetOutputFormat(cmd, internal.FormatText, internal.FormatJSON)
+		if err != nil {
+			return err
+		}
+
+		path, err := internal.GetKubeconfigPath(cmd)
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 1 {
+			path = args[0]
+		}
+
+		config, err := internal.ReadConf(path)
+		if err != nil {
+			return err
+		}
+
+		findings := internal.Validate(config, filepath.Dir(path))
+
+		if format == internal.FormatJSON {
+			err = internal.PrintJSON(findings)
+			if err != nil {
+				return err
+			}
+		} else {
+			internal.PrintFindings(findings)
+		}
+
+		if internal.HasErrors(findings) {
+			return internal.WithExitCode(internal.ExitValidation, internal.ErrFindings)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	validateCmd.Flags().StringP(internal.OptionOutput, "o", internal.FormatText, "output format: text or json")
+	rootCmd.AddCommand(validateCmd)
+}
@@ -0,0 +1,158 @@
+package internal
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultExecAPIVersion is apiVersion of exec credential plugins set by default
+const DefaultExecAPIVersion = "client.authentication.k8s.io/v1beta1"
+
+// ExecAPIVersionV1 is apiVersion of exec credential plugins which requires interactiveMode to be set
+const ExecAPIVersionV1 = "client.authentication.k8s.io/v1"
+
+// DefaultExecInteractiveMode is interactiveMode set for plugins of ExecAPIVersionV1, same as kubectl assumes for older ones
+const DefaultExecInteractiveMode = "IfAvailable"
+
+// ClusterOptions describe cluster created by NewCluster
+type ClusterOptions struct {
+	Server                string
+	CAFile                string
+	InsecureSkipTLSVerify bool
+	// Embed inlines content of CA file instead of referencing it
+	Embed bool
+}
+
+// NewCluster builds cluster entry. Referenced files are stored with absolute paths
+// like kubectl does, so kubeconfig keeps working if it is moved
+func NewCluster(opts ClusterOptions) (Cluster, error) {
+	if opts.Server == "" {
+		return Cluster{}, WithExitCode(ExitUsage, errors.New("server is required"))
+	}
+
+	if opts.InsecureSkipTLSVerify && opts.CAFile != "" {
+		return Cluster{}, WithExitCode(ExitUsage, errors.New("CA file cannot be used with insecure-skip-tls-verify"))
+	}
+
+	cluster := Cluster{Server: opts.Server, InsecureSkipTLSVerify: opts.InsecureSkipTLSVerify}
+
+	err := referenceFile(opts.CAFile, opts.Embed, &cluster.CertificateAuthority, &cluster.CertificateAuthorityData)
+	if err != nil {
+		return Cluster{}, err
+	}
+
+	return cluster, nil
+}
+
+// UserOptions describe user created by NewUser. Exactly one way to authenticate
+// must be set: token, token file, client certificate with key or exec plugin
+type UserOptions struct {
+	Token          string
+	TokenFile      string
+	ClientCert     string
+	ClientKey      string
+	ExecCommand    string
+	ExecArgs       []string
+	ExecEnv        []string
+	ExecAPIVersion string
+	// Embed inlines content of certificate and key files instead of referencing them
+	Embed bool
+}
+
+// NewUser builds user entry. Referenced files are stored with absolute paths
+func NewUser(opts UserOptions) (User, error) {
+	methods := 0
+	for _, set := range []bool{opts.Token != "", opts.TokenFile != "", opts.ClientCert != "" || opts.ClientKey != "", opts.ExecCommand != ""} {
+		if set {
+			methods++
+		}
+	}
+
+	if methods != 1 {
+		return User{}, WithExitCode(ExitUsage, errors.New(
+			"set exactly one of token, token file, client certificate with key or exec command",
+		))
+	}
+
+	if (opts.ClientCert == "") != (opts.ClientKey == "") {
+		return User{}, WithExitCode(ExitUsage, errors.New("client certificate and key must be set together"))
+	}
+
+	if opts.ExecCommand == "" && (len(opts.ExecArgs) > 0 || len(opts.ExecEnv) > 0) {
+		return User{}, WithExitCode(ExitUsage, errors.New("exec args and env require exec command"))
+	}
+
+	user := User{Token: opts.Token}
+
+	if opts.TokenFile != "" {
+		path, err := filepath.Abs(opts.TokenFile)
+		if err != nil {
+			return User{}, err
+		}
+		user.TokenFile = path
+	}
+
+	err := referenceFile(opts.ClientCert, opts.Embed, &user.ClientCertificate, &user.ClientCertificateData)
+	if err == nil {
+		err = referenceFile(opts.ClientKey, opts.Embed, &user.ClientKey, &user.ClientKeyData)
+	}
+	if err != nil {
+		return User{}, err
+	}
+
+	if opts.ExecCommand != "" {
+		user.Exec = &ExecConfig{
+			APIVersion: opts.ExecAPIVersion,
+			Command:    opts.ExecCommand,
+			Args:       opts.ExecArgs,
+		}
+
+		if user.Exec.APIVersion == "" {
+			user.Exec.APIVersion = DefaultExecAPIVersion
+		}
+
+		if user.Exec.APIVersion == ExecAPIVersionV1 {
+			user.Exec.InteractiveMode = DefaultExecInteractiveMode
+		}
+
+		for _, env := range opts.ExecEnv {
+			parts := strings.SplitN(env, "=", 2)
+			if len(parts) != 2 || parts[0] == "" {
+				return User{}, WithExitCode(ExitUsage, fmt.Errorf("exec env %q is not in NAME=VALUE form", env))
+			}
+
+			user.Exec.Env = append(user.Exec.Env, ExecEnvVar{Name: parts[0], Value: parts[1]})
+		}
+	}
+
+	return user, nil
+}
+
+// referenceFile sets path to absolute path of file, or data to its base64 encoded content if embed is set
+func referenceFile(file string, embed bool, path, data *string) error {
+	if file == "" {
+		return nil
+	}
+
+	if embed {
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		*data = base64.StdEncoding.EncodeToString(raw)
+		return nil
+	}
+
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+
+	*path = abs
+	return nil
+}
@@ -0,0 +1,75 @@
+package internal
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCluster(t *testing.T) {
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.crt")
+	require.NoError(t, os.WriteFile(caFile, []byte("ca"), 0600))
+
+	cluster, err := NewCluster(ClusterOptions{Server: "https://prod", CAFile: caFile})
+	require.NoError(t, err)
+	require.Equal(t, Cluster{Server: "https://prod", CertificateAuthority: caFile}, cluster)
+
+	cluster, err = NewCluster(ClusterOptions{Server: "https://prod", CAFile: caFile, Embed: true})
+	require.NoError(t, err)
+	require.Equal(t, Cluster{Server: "https://prod", CertificateAuthorityData: base64.StdEncoding.EncodeToString([]byte("ca"))}, cluster)
+
+	_, err = NewCluster(ClusterOptions{Server: "https://prod", CAFile: filepath.Join(dir, "missing"), Embed: true})
+	require.Equal(t, ExitNotFound, ExitCode(err))
+
+	_, err = NewCluster(ClusterOptions{Server: "https://prod", CAFile: caFile, InsecureSkipTLSVerify: true})
+	require.Equal(t, ExitUsage, ExitCode(err))
+}
+
+func TestNewUser(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	require.NoError(t, os.WriteFile(certFile, []byte("cert"), 0600))
+	require.NoError(t, os.WriteFile(keyFile, []byte("key"), 0600))
+
+	user, err := NewUser(UserOptions{ClientCert: certFile, ClientKey: keyFile, Embed: true})
+	require.NoError(t, err)
+	require.Equal(t, User{
+		ClientCertificateData: base64.StdEncoding.EncodeToString([]byte("cert")),
+		ClientKeyData:         base64.StdEncoding.EncodeToString([]byte("key")),
+	}, user)
+
+	user, err = NewUser(UserOptions{
+		ExecCommand: "aws", ExecArgs: []string{"eks", "get-token"}, ExecEnv: []string{"AWS_PROFILE=prod"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, User{Exec: &ExecConfig{
+		APIVersion: DefaultExecAPIVersion,
+		Command:    "aws",
+		Args:       []string{"eks", "get-token"},
+		Env:        []ExecEnvVar{{Name: "AWS_PROFILE", Value: "prod"}},
+	}}, user)
+
+	user, err = NewUser(UserOptions{ExecCommand: "kubelogin", ExecAPIVersion: ExecAPIVersionV1})
+	require.NoError(t, err)
+	require.Equal(t, User{Exec: &ExecConfig{
+		APIVersion:      ExecAPIVersionV1,
+		Command:         "kubelogin",
+		InteractiveMode: DefaultExecInteractiveMode,
+	}}, user)
+
+	for _, opts := range []UserOptions{
+		{},
+		{Token: "abc", TokenFile: "token"},
+		{ClientCert: certFile},
+		{Token: "abc", ExecArgs: []string{"eks"}},
+		{ExecCommand: "aws", ExecEnv: []string{"AWS_PROFILE"}},
+	} {
+		_, err = NewUser(opts)
+		require.Equal(t, ExitUsage, ExitCode(err), "%+v", opts)
+	}
+}
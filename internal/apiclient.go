@@ -0,0 +1,177 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// apiTimeout limits single request to API server
+const apiTimeout = 30 * time.Second
+
+// APIClient is minimal client of k8s API server authenticating as user of kubeconfig context
+type APIClient struct {
+	Server string
+	HTTP   *http.Client
+
+	token    string
+	username string
+	password string
+}
+
+// apiStatus is k8s Status object returned with errors
+type apiStatus struct {
+	Message string `json:"message"`
+}
+
+// NewAPIClient creates client for context of kubeconfig, current one if context is
+// empty. Relative paths are resolved against dir. Exec credential plugins are not supported
+func NewAPIClient(k Kubeconfig, context, dir string) (*APIClient, error) {
+	minified, err := Minify(k, context)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(minified.Users) == 0 {
+		return nil, WithExitCode(ExitValidation, fmt.Errorf("context %s has no user", minified.CurrentContext))
+	}
+
+	minified, err = Flatten(minified, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	cluster := minified.Clusters[0].Cluster
+	user := minified.Users[0].User
+
+	if user.Exec != nil {
+		return nil, WithExitCode(ExitUsage, fmt.Errorf(
+			"user %s uses exec credential plugin, which is not supported, use context with certificate or token",
+			minified.Users[0].Name,
+		))
+	}
+
+	// auth-provider users and users without credentials would get confusing 401 from API
+	if user.Token == "" && user.Username == "" && user.ClientCertificateData == "" {
+		return nil, WithExitCode(ExitUsage, fmt.Errorf(
+			"user %s has no token, basic auth or client certificate, use context with certificate or token",
+			minified.Users[0].Name,
+		))
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: cluster.InsecureSkipTLSVerify}
+
+	if cluster.CertificateAuthorityData != "" {
+		ca, err := base64.StdEncoding.DecodeString(cluster.CertificateAuthorityData)
+		if err != nil {
+			return nil, WithExitCode(ExitValidation, fmt.Errorf("invalid certificate-authority-data: %w", err))
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, WithExitCode(ExitValidation, errors.New("no certificates found in certificate authority"))
+		}
+	}
+
+	if user.ClientCertificateData != "" {
+		cert, err := base64.StdEncoding.DecodeString(user.ClientCertificateData)
+		if err != nil {
+			return nil, WithExitCode(ExitValidation, fmt.Errorf("invalid client-certificate-data: %w", err))
+		}
+
+		key, err := base64.StdEncoding.DecodeString(user.ClientKeyData)
+		if err != nil {
+			return nil, WithExitCode(ExitValidation, fmt.Errorf("invalid client-key-data: %w", err))
+		}
+
+		pair, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			return nil, WithExitCode(ExitValidation, fmt.Errorf("invalid client certificate: %w", err))
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{pair}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &APIClient{
+		Server:   strings.TrimRight(cluster.Server, "/"),
+		HTTP:     &http.Client{Transport: transport, Timeout: apiTimeout},
+		token:    user.Token,
+		username: user.Username,
+		password: user.Password,
+	}, nil
+}
+
+// Do sends request with body encoded as JSON to API path and decodes response into result
+func (c *APIClient) Do(method, path string, body, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(raw)
+	}
+
+	req, err := http.NewRequest(method, c.Server+path, reader)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	switch {
+	case c.token != "":
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	case c.username != "":
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		status := apiStatus{}
+		if json.Unmarshal(raw, &status) != nil || status.Message == "" {
+			status.Message = strings.TrimSpace(string(raw))
+		}
+
+		err = fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, status.Message)
+
+		switch resp.StatusCode {
+		case http.StatusNotFound:
+			return WithExitCode(ExitNotFound, err)
+		case http.StatusConflict:
+			return WithExitCode(ExitConflict, err)
+		default:
+			return err
+		}
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	return json.Unmarshal(raw, result)
+}
@@ -0,0 +1,101 @@
+package internal
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// ErrAborted is returned when user declines to apply changes
+var ErrAborted = errors.New("aborted")
+
+// errChangedMeanwhile is returned when kubeconfig changes while user looks at preview
+var errChangedMeanwhile = errors.New("kubeconfig was changed while waiting for confirmation, run command again")
+
+// Confirm asks yes/no question, reading answer from in. Anything but yes means no
+func Confirm(in io.Reader, question string) (bool, error) {
+	fmt.Fprintf(os.Stderr, "%s [y/N]: ", question)
+
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// ApplyChanges previews how update changes kubeconfig at path, asks for confirmation
+// unless --yes is set and stores the result under lock. With --dry-run only preview
+// is shown. If kubeconfig is changed by someone else before confirmation, nothing is written
+func ApplyChanges(cmd *cobra.Command, path string, update func(Kubeconfig) (Kubeconfig, error)) error {
+	yes, err := cmd.Flags().GetBool(OptionYes)
+	if err != nil {
+		return err
+	}
+
+	dryRun, err := cmd.Flags().GetBool(OptionDryRun)
+	if err != nil {
+		return err
+	}
+
+	timeout, err := GetLockTimeout(cmd)
+	if err != nil {
+		return err
+	}
+
+	before, err := ReadConf(path)
+	if err != nil {
+		return err
+	}
+
+	after, err := update(before)
+	if err != nil {
+		return err
+	}
+
+	changes, err := Diff(before, after)
+	if err != nil {
+		return err
+	}
+
+	if len(changes) == 0 {
+		fmt.Println("nothing to change")
+		return nil
+	}
+
+	PrintDiff(changes)
+
+	if dryRun {
+		return nil
+	}
+
+	if !yes {
+		ok, err := Confirm(cmd.InOrStdin(), "apply changes?")
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return ErrAborted
+		}
+	}
+
+	return UpdateConf(path, path, timeout, func(current Kubeconfig) (Kubeconfig, error) {
+		if !reflect.DeepEqual(current, before) {
+			return current, WithExitCode(ExitConflict, errChangedMeanwhile)
+		}
+
+		return after, nil
+	})
+}
@@ -0,0 +1,151 @@
+package internal
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// AuditRule describes single security check of kubeconfig
+type AuditRule struct {
+	ID          string
+	Severity    Severity
+	Description string
+}
+
+// Audit rules. IDs are used in ignore lists, so never renumber them
+var (
+	RuleInsecureSkipTLSVerify = AuditRule{"KA001", SeverityError, "TLS verification of cluster is disabled with insecure-skip-tls-verify"}
+	RulePlainHTTP             = AuditRule{"KA002", SeverityError, "cluster is accessed over plain http"}
+	RuleStaticToken           = AuditRule{"KA003", SeverityWarning, "user authenticates with static long-lived token"}
+	RuleBasicAuth             = AuditRule{"KA004", SeverityError, "user authenticates with basic-auth password"}
+	RuleInlineClientKey       = AuditRule{"KA005", SeverityWarning, "client key is stored inline in kubeconfig"}
+	RuleKubeconfigPermissions = AuditRule{"KA006", SeverityError, "kubeconfig is readable by group or others"}
+	RuleKeyFilePermissions    = AuditRule{"KA007", SeverityError, "referenced key or token file is readable by group or others"}
+	RuleRelativeExecCommand   = AuditRule{"KA008", SeverityWarning, "exec plugin command is a relative path"}
+)
+
+// AuditRules lists all audit rules
+var AuditRules = []AuditRule{
+	RuleInsecureSkipTLSVerify,
+	RulePlainHTTP,
+	RuleStaticToken,
+	RuleBasicAuth,
+	RuleInlineClientKey,
+	RuleKubeconfigPermissions,
+	RuleKeyFilePermissions,
+	RuleRelativeExecCommand,
+}
+
+// Audit looks for risky settings in kubeconfig stored at path.
+// Findings of rules with ids listed in ignore are skipped
+func Audit(k Kubeconfig, path string, ignore []string) []Finding {
+	a := auditor{ignore: map[string]bool{}, findings: []Finding{}}
+	for _, id := range ignore {
+		a.ignore[strings.ToUpper(strings.TrimSpace(id))] = true
+	}
+
+	dir := filepath.Dir(path)
+	a.checkPermissions(RuleKubeconfigPermissions, "kubeconfig", "", "", path)
+
+	for _, entry := range k.Clusters {
+		if entry.Cluster.InsecureSkipTLSVerify {
+			a.add(RuleInsecureSkipTLSVerify, "cluster", entry.Name, "insecure-skip-tls-verify", "")
+		}
+
+		u, err := url.Parse(entry.Cluster.Server)
+		if err == nil && u.Scheme == "http" {
+			a.add(RulePlainHTTP, "cluster", entry.Name, "server", entry.Cluster.Server)
+		}
+	}
+
+	for _, entry := range k.Users {
+		user := entry.User
+
+		if user.Token != "" && !isShortLivedToken(user.Token) {
+			a.add(RuleStaticToken, "user", entry.Name, "token", "")
+		}
+
+		// token files are usually rotated, like projected service account tokens
+		if user.TokenFile != "" {
+			a.checkPermissions(RuleKeyFilePermissions, "user", entry.Name, "tokenFile", ResolvePath(dir, user.TokenFile))
+		}
+
+		if user.Password != "" {
+			a.add(RuleBasicAuth, "user", entry.Name, "password", "")
+		}
+
+		if user.ClientKeyData != "" {
+			a.add(RuleInlineClientKey, "user", entry.Name, "client-key-data", "")
+		}
+
+		if user.ClientKey != "" {
+			a.checkPermissions(RuleKeyFilePermissions, "user", entry.Name, "client-key", ResolvePath(dir, user.ClientKey))
+		}
+
+		if user.Exec != nil && user.Exec.Command != "" && !filepath.IsAbs(user.Exec.Command) &&
+			strings.ContainsRune(user.Exec.Command, filepath.Separator) {
+			a.add(RuleRelativeExecCommand, "user", entry.Name, "exec.command", user.Exec.Command)
+		}
+	}
+
+	return a.findings
+}
+
+// shortLivedTokenAge is how long token may stay valid to not be reported as long-lived
+const shortLivedTokenAge = 24 * time.Hour
+
+// isShortLivedToken reports whether token is JWT which expires soon
+func isShortLivedToken(token string) bool {
+	claims, err := DecodeJWT(token)
+	if err != nil || claims.ExpiresAt == nil {
+		return false
+	}
+
+	return time.Until(time.Unix(int64(*claims.ExpiresAt), 0)) <= shortLivedTokenAge
+}
+
+type auditor struct {
+	ignore   map[string]bool
+	findings []Finding
+}
+
+func (a *auditor) add(rule AuditRule, section, name, field, details string) {
+	if a.ignore[rule.ID] {
+		return
+	}
+
+	message := rule.Description
+	if details != "" {
+		message = fmt.Sprintf("%s: %s", message, details)
+	}
+
+	a.findings = append(a.findings, Finding{
+		Rule:     rule.ID,
+		Severity: rule.Severity,
+		Section:  section,
+		Name:     name,
+		Field:    field,
+		Message:  message,
+	})
+}
+
+func (a *auditor) checkPermissions(rule AuditRule, section, name, field, path string) {
+	// file modes do not describe access rights on windows
+	if runtime.GOOS == "windows" {
+		return
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+
+	if info.Mode().Perm()&0044 != 0 {
+		a.add(rule, section, name, field, fmt.Sprintf("%s has mode %#o", path, info.Mode().Perm()))
+	}
+}
@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAudit(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+	require.NoError(t, os.WriteFile(path, nil, 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "key.pem"), nil, 0600))
+	require.NoError(t, os.Chmod(filepath.Join(dir, "key.pem"), 0644))
+
+	config := Kubeconfig{
+		Clusters: []ClusterEntry{
+			{Name: "insecure", Cluster: Cluster{Server: "https://a", InsecureSkipTLSVerify: true}},
+			{Name: "http", Cluster: Cluster{Server: "http://b"}},
+			{Name: "fine", Cluster: Cluster{Server: "https://c"}},
+		},
+		Users: []UserEntry{
+			{Name: "token", User: User{Token: "abc"}},
+			{Name: "jwt", User: User{Token: testJWT(t, map[string]interface{}{"exp": time.Now().Add(365 * 24 * time.Hour).Unix()})}},
+			{Name: "short", User: User{Token: testJWT(t, map[string]interface{}{"exp": time.Now().Add(time.Hour).Unix()})}},
+			{Name: "rotated", User: User{TokenFile: "token"}},
+			{Name: "basic", User: User{Username: "admin", Password: "admin"}},
+			{Name: "inline", User: User{ClientCertificateData: "QUJD", ClientKeyData: "QUJD"}},
+			{Name: "files", User: User{ClientCertificate: "cert.pem", ClientKey: "key.pem"}},
+			{Name: "exec", User: User{Exec: &ExecConfig{Command: "bin/plugin"}}},
+			{Name: "path", User: User{Exec: &ExecConfig{Command: "aws"}}},
+		},
+	}
+
+	rules := func(findings []Finding) []string {
+		ids := []string{}
+		for _, finding := range findings {
+			ids = append(ids, finding.Rule+" "+finding.Name)
+		}
+		return ids
+	}
+
+	expected := []string{
+		"KA001 insecure",
+		"KA002 http",
+		"KA003 token",
+		"KA003 jwt",
+		"KA004 basic",
+		"KA005 inline",
+		"KA008 exec",
+	}
+	if runtime.GOOS != "windows" {
+		expected = append(expected, "KA007 files")
+	}
+
+	require.ElementsMatch(t, expected, rules(Audit(config, path, nil)))
+	require.ElementsMatch(t, []string{"KA002 http", "KA004 basic"},
+		rules(Audit(config, path, []string{"ka001", "KA003", "KA005", "KA007", "KA008"})))
+
+	sarif := NewSARIFLog(Audit(config, path, nil), path)
+	require.Len(t, sarif.Runs[0].Tool.Driver.Rules, len(AuditRules))
+	require.Len(t, sarif.Runs[0].Results, len(expected))
+}
@@ -0,0 +1,194 @@
+package internal
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	p "path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// CreateBundle stores kubeconfig from kubeconfigPath and every file it references
+// into gzipped tar archive at bundlePath. File references inside bundled kubeconfig
+// are rewritten to point to the copies inside the bundle
+func CreateBundle(kubeconfigPath, bundlePath string) error {
+	raw, err := os.ReadFile(kubeconfigPath)
+	if err != nil {
+		return fmt.Errorf("cannot open kubeconfig: %w", err)
+	}
+
+	original, err := ParseConf(raw)
+	if err != nil {
+		return err
+	}
+
+	config, err := ParseConf(raw)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(kubeconfigPath)
+	files := map[string]string{}
+	order := []string{}
+
+	for _, ref := range FileReferences(&config) {
+		src := ResolvePath(dir, *ref)
+
+		name, ok := files[src]
+		if !ok {
+			name = p.Join(BundleFilesFolder, fmt.Sprintf("%d-%s", len(files), filepath.Base(src)))
+			files[src] = name
+			order = append(order, src)
+		}
+
+		*ref = name
+	}
+
+	// only references are rewritten, so fields konfig does not know are bundled too
+	raw, err = PatchConf(raw, original, config)
+	if err != nil {
+		return err
+	}
+
+	out := bytes.Buffer{}
+	gz := gzip.NewWriter(&out)
+	tw := tar.NewWriter(gz)
+
+	err = writeTarEntry(tw, BundleConfigName, raw, 0600)
+	if err != nil {
+		return err
+	}
+
+	for _, src := range order {
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return fmt.Errorf("cannot read referenced file: %w", err)
+		}
+
+		// exec plugins must stay executable
+		mode := int64(0600)
+		if info, err := os.Stat(src); err == nil && info.Mode()&0100 != 0 {
+			mode = 0700
+		}
+
+		err = writeTarEntry(tw, files[src], data, mode)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tw.Close()
+	if err != nil {
+		return err
+	}
+
+	err = gz.Close()
+	if err != nil {
+		return err
+	}
+
+	return WriteFileAtomic(bundlePath, out.Bytes(), os.FileMode(0600))
+}
+
+// ExtractBundle unpacks files from bundle at bundlePath into dir and returns content
+// of bundled kubeconfig with file references pointing to the unpacked files
+func ExtractBundle(bundlePath, dir string) ([]byte, error) {
+	in, err := os.Open(bundlePath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open bundle: %w", err)
+	}
+	defer in.Close()
+
+	gz, err := gzip.NewReader(in)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read bundle: %w", err)
+	}
+
+	var raw []byte
+	tr := tar.NewReader(gz)
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot read bundle: %w", err)
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := p.Clean(header.Name)
+		if name == BundleConfigName {
+			raw, err = io.ReadAll(tr)
+			if err != nil {
+				return nil, fmt.Errorf("cannot read bundle: %w", err)
+			}
+			continue
+		}
+
+		if !strings.HasPrefix(name, BundleFilesFolder+"/") {
+			return nil, fmt.Errorf("unexpected file in bundle: %s", header.Name)
+		}
+
+		dst := filepath.Join(dir, filepath.FromSlash(name))
+		err = os.MkdirAll(filepath.Dir(dst), os.FileMode(0700))
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read bundle: %w", err)
+		}
+
+		err = WriteFileAtomic(dst, data, os.FileMode(header.Mode&0700|0600))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if raw == nil {
+		return nil, fmt.Errorf("bundle %s contains no kubeconfig", bundlePath)
+	}
+
+	bundled, err := ParseConf(raw)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read bundled kubeconfig: %w", err)
+	}
+
+	config, err := ParseConf(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ref := range FileReferences(&config) {
+		if strings.HasPrefix(*ref, BundleFilesFolder+"/") {
+			*ref = filepath.Join(dir, filepath.FromSlash(*ref))
+		}
+	}
+
+	return PatchConf(raw, bundled, config)
+}
+
+func writeTarEntry(tw *tar.Writer, name string, data []byte, mode int64) error {
+	err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    mode,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = tw.Write(data)
+	return err
+}
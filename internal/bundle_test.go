@@ -0,0 +1,93 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBundle(t *testing.T) {
+	src := t.TempDir()
+
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "certs"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "certs", "ca.crt"), []byte("ca"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "key.pem"), []byte("key"), 0600))
+
+	kubeconfig := filepath.Join(src, "config")
+	require.NoError(t, os.WriteFile(kubeconfig, []byte(`
+apiVersion: v1
+kind: Config
+clusters:
+- name: dev
+  cluster:
+    server: https://dev.example.com
+    certificate-authority: certs/ca.crt
+- name: prod
+  cluster:
+    server: https://prod.example.com
+    certificate-authority: `+filepath.Join(src, "certs", "ca.crt")+`
+users:
+- name: dev
+  user:
+    client-key: ./key.pem
+`), 0600))
+
+	bundle := filepath.Join(t.TempDir(), "backup.tar.gz")
+	require.NoError(t, CreateBundle(kubeconfig, bundle))
+
+	dst := t.TempDir()
+	raw, err := ExtractBundle(bundle, dst)
+	require.NoError(t, err)
+
+	config, err := ParseConf(raw)
+	require.NoError(t, err)
+
+	require.Equal(t, config.Clusters[0].Cluster.CertificateAuthority, config.Clusters[1].Cluster.CertificateAuthority)
+
+	refs := FileReferences(&config)
+	require.Len(t, refs, 3)
+
+	expected := []string{"ca", "ca", "key"}
+	for i, ref := range refs {
+		require.True(t, filepath.IsAbs(*ref))
+		require.True(t, strings.HasPrefix(*ref, dst))
+
+		data, err := os.ReadFile(*ref)
+		require.NoError(t, err)
+		require.Equal(t, expected[i], string(data))
+	}
+}
+
+func TestBundleKeepsUnknownFields(t *testing.T) {
+	src := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(src, "ca.crt"), []byte("ca"), 0600))
+
+	kubeconfig := filepath.Join(src, "config")
+	require.NoError(t, os.WriteFile(kubeconfig, []byte(unknownFieldsConf+`preferences:
+  colors: true
+`), 0600))
+
+	require.NoError(t, UpdateConf(kubeconfig, kubeconfig, DefaultLockTimeout, func(config Kubeconfig) (Kubeconfig, error) {
+		config.Clusters[0].Cluster.CertificateAuthority = "ca.crt"
+		return config, nil
+	}))
+
+	bundle := filepath.Join(t.TempDir(), "backup.tar.gz")
+	require.NoError(t, CreateBundle(kubeconfig, bundle))
+
+	dst := t.TempDir()
+	raw, err := ExtractBundle(bundle, dst)
+	require.NoError(t, err)
+	requireUnknownFieldsKept(t, raw)
+	require.Contains(t, string(raw), "preferences:\n  colors: true\n")
+
+	config, err := ParseConf(raw)
+	require.NoError(t, err)
+
+	refs := FileReferences(&config)
+	require.Len(t, refs, 1)
+	require.True(t, strings.HasPrefix(*refs[0], dst))
+}
@@ -0,0 +1,229 @@
+package internal
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// ParseCertificates parses all PEM encoded certificates from data
+func ParseCertificates(data []byte) ([]*x509.Certificate, error) {
+	certs := []*x509.Certificate{}
+
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse certificate: %w", err)
+		}
+
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, errors.New("no PEM encoded certificates found")
+	}
+
+	return certs, nil
+}
+
+// ParsePrivateKey parses PEM encoded private key in PKCS#1, PKCS#8 or SEC 1 form
+func ParsePrivateKey(data []byte) (crypto.PrivateKey, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("no PEM encoded private key found")
+		}
+
+		switch block.Type {
+		case "RSA PRIVATE KEY":
+			return x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			return x509.ParseECPrivateKey(block.Bytes)
+		case "PRIVATE KEY":
+			return x509.ParsePKCS8PrivateKey(block.Bytes)
+		}
+	}
+}
+
+// CertificateInfo describes certificate found in kubeconfig
+type CertificateInfo struct {
+	Section     string     `json:"section"`
+	Name        string     `json:"name"`
+	Field       string     `json:"field"`
+	Subject     string     `json:"subject,omitempty"`
+	Issuer      string     `json:"issuer,omitempty"`
+	SANs        []string   `json:"sans,omitempty"`
+	Groups      []string   `json:"groups,omitempty"`
+	Fingerprint string     `json:"fingerprint,omitempty"`
+	NotBefore   *time.Time `json:"notBefore,omitempty"`
+	NotAfter    *time.Time `json:"notAfter,omitempty"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// ExpiresWithin reports whether certificate is expired or expires within d from now
+func (c CertificateInfo) ExpiresWithin(d time.Duration) bool {
+	return c.Error == "" && c.NotAfter != nil && time.Now().Add(d).After(*c.NotAfter)
+}
+
+// ExpiringCertificates returns certificates which expire within d from now together
+// with ones which cannot be read, so broken certificates are not silently skipped
+func ExpiringCertificates(infos []CertificateInfo, d time.Duration) []CertificateInfo {
+	expiring := []CertificateInfo{}
+	for _, info := range infos {
+		if info.Error != "" || info.ExpiresWithin(d) {
+			expiring = append(expiring, info)
+		}
+	}
+
+	return expiring
+}
+
+// InspectCertificates decodes every certificate authority and client certificate
+// of kubeconfig, either inline or referenced by path relative to dir
+func InspectCertificates(k Kubeconfig, dir string) []CertificateInfo {
+	infos := []CertificateInfo{}
+
+	for _, entry := range k.Clusters {
+		infos = append(infos, inspectCertificateField(
+			"cluster", entry.Name, "certificate-authority", entry.Cluster.CertificateAuthority,
+			entry.Cluster.CertificateAuthorityData, dir,
+		)...)
+	}
+
+	for _, entry := range k.Users {
+		infos = append(infos, inspectCertificateField(
+			"user", entry.Name, "client-certificate", entry.User.ClientCertificate,
+			entry.User.ClientCertificateData, dir,
+		)...)
+	}
+
+	return infos
+}
+
+func inspectCertificateField(section, name, field, path, data, dir string) []CertificateInfo {
+	var content []byte
+	var err error
+
+	switch {
+	case data != "":
+		field += "-data"
+		content, err = base64.StdEncoding.DecodeString(data)
+	case path != "":
+		content, err = os.ReadFile(ResolvePath(dir, path))
+	default:
+		return nil
+	}
+
+	var certs []*x509.Certificate
+	if err == nil {
+		certs, err = ParseCertificates(content)
+	}
+
+	if err != nil {
+		return []CertificateInfo{{Section: section, Name: name, Field: field, Error: err.Error()}}
+	}
+
+	infos := []CertificateInfo{}
+	for _, cert := range certs {
+		info := DescribeCertificate(cert)
+		info.Section = section
+		info.Name = name
+		info.Field = field
+
+		infos = append(infos, info)
+	}
+
+	return infos
+}
+
+// DescribeCertificate returns human readable details of certificate
+func DescribeCertificate(cert *x509.Certificate) CertificateInfo {
+	sans := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+	sans = append(sans, cert.EmailAddresses...)
+
+	notBefore, notAfter := cert.NotBefore, cert.NotAfter
+
+	return CertificateInfo{
+		Subject:     cert.Subject.String(),
+		Issuer:      cert.Issuer.String(),
+		SANs:        sans,
+		Groups:      cert.Subject.Organization,
+		Fingerprint: CertificateFingerprint(cert),
+		NotBefore:   &notBefore,
+		NotAfter:    &notAfter,
+	}
+}
+
+// CertificateFingerprint returns sha256 fingerprint of DER form of certificate, as openssl shows it
+func CertificateFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	fingerprint := make([]string, len(sum))
+	for i, b := range sum {
+		fingerprint[i] = fmt.Sprintf("%02X", b)
+	}
+
+	return strings.Join(fingerprint, ":")
+}
+
+// PrintCertificates prints certificate details, highlighting ones expiring within warn
+func PrintCertificates(infos []CertificateInfo, warn time.Duration) {
+	magenta := color.New(color.FgMagenta)
+	red := color.New(color.FgRed)
+
+	for _, info := range infos {
+		magenta.Printf("%s %s %s\n", info.Section, info.Name, info.Field)
+
+		if info.Error != "" || info.NotAfter == nil {
+			red.Printf("  error:       %s\n", info.Error)
+			continue
+		}
+
+		fmt.Printf("  subject:     %s\n", info.Subject)
+		fmt.Printf("  issuer:      %s\n", info.Issuer)
+		if len(info.SANs) > 0 {
+			fmt.Printf("  sans:        %s\n", strings.Join(info.SANs, ", "))
+		}
+		if len(info.Groups) > 0 {
+			fmt.Printf("  groups:      %s\n", strings.Join(info.Groups, ", "))
+		}
+		fmt.Printf("  fingerprint: %s\n", info.Fingerprint)
+
+		left := time.Until(*info.NotAfter)
+		notAfter := fmt.Sprintf("  not after:   %s", info.NotAfter.Local().Format(time.RFC3339))
+
+		switch {
+		case left <= 0:
+			red.Printf("%s (expired)\n", notAfter)
+		case info.ExpiresWithin(warn):
+			red.Printf("%s (expires in %s)\n", notAfter, HumanDuration(left))
+		default:
+			fmt.Printf("%s (expires in %s)\n", notAfter, HumanDuration(left))
+		}
+	}
+}
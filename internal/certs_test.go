@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspectCertificates(t *testing.T) {
+	dir := t.TempDir()
+
+	ca, _ := testCertificate(t, pkix.Name{CommonName: "kubernetes"}, time.Now().Add(365*24*time.Hour))
+	client, _ := testCertificate(t, pkix.Name{CommonName: "admin", Organization: []string{"system:masters"}},
+		time.Now().Add(24*time.Hour))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ca.crt"), ca, 0600))
+
+	config := Kubeconfig{
+		Clusters: []ClusterEntry{
+			{Name: "dev", Cluster: Cluster{CertificateAuthority: "ca.crt"}},
+			{Name: "broken", Cluster: Cluster{CertificateAuthorityData: "QUJD"}},
+		},
+		Users: []UserEntry{
+			{Name: "admin", User: User{ClientCertificateData: base64.StdEncoding.EncodeToString(client)}},
+			{Name: "token", User: User{Token: "abc"}},
+		},
+	}
+
+	infos := InspectCertificates(config, dir)
+	require.Len(t, infos, 3)
+
+	require.Equal(t, "certificate-authority", infos[0].Field)
+	require.Equal(t, "CN=kubernetes", infos[0].Subject)
+	require.False(t, infos[0].ExpiresWithin(30*24*time.Hour))
+
+	require.Equal(t, "certificate-authority-data", infos[1].Field)
+	require.NotEmpty(t, infos[1].Error)
+	require.False(t, infos[1].ExpiresWithin(30*24*time.Hour))
+
+	require.Equal(t, "user", infos[2].Section)
+	require.Equal(t, []string{"system:masters"}, infos[2].Groups)
+	require.Equal(t, []string{"kubernetes.default"}, infos[2].SANs)
+	require.Len(t, infos[2].Fingerprint, 32*3-1)
+	require.True(t, infos[2].ExpiresWithin(30*24*time.Hour))
+	require.False(t, infos[2].ExpiresWithin(time.Hour))
+
+	// dates of certificates which cannot be read are left out instead of zero time
+	raw, err := json.Marshal(infos[1])
+	require.NoError(t, err)
+	require.NotContains(t, string(raw), "notAfter")
+	require.NotContains(t, string(raw), "notBefore")
+
+	raw, err = json.Marshal(infos[2])
+	require.NoError(t, err)
+	require.Contains(t, string(raw), `"notAfter":"`)
+
+	expiring := ExpiringCertificates(infos, 30*24*time.Hour)
+	require.Len(t, expiring, 2)
+	require.Equal(t, "broken", expiring[0].Name)
+	require.Equal(t, "admin", expiring[1].Name)
+}
+
+func TestParseDuration(t *testing.T) {
+	d, err := ParseDuration("30d")
+	require.NoError(t, err)
+	require.Equal(t, 30*24*time.Hour, d)
+
+	d, err = ParseDuration("12h")
+	require.NoError(t, err)
+	require.Equal(t, 12*time.Hour, d)
+
+	_, err = ParseDuration("xd")
+	require.Error(t, err)
+}
@@ -0,0 +1,225 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// errAsGroupWithoutAs is returned when groups are impersonated without user
+var errAsGroupWithoutAs = errors.New("impersonating groups requires user to impersonate, set --as")
+
+// CloneOptions are overrides for context created by CloneContext
+type CloneOptions struct {
+	// Namespace replaces namespace of context
+	Namespace string
+	// User replaces user of context
+	User string
+	// As and AsGroups set impersonation, user is duplicated for that.
+	// Groups alone keep user impersonated by source user, while new As
+	// drops uid and extra of user impersonated before
+	As       string
+	AsGroups []string
+	// SetCurrent makes new context current
+	SetCurrent bool
+}
+
+// CloneContext returns copy of kubeconfig with context dst, which is a copy of
+// context src with options applied. If impersonation is requested, user of context
+// is duplicated under name dst with impersonation fields set
+func CloneContext(k Kubeconfig, src, dst string, opts CloneOptions) (Kubeconfig, error) {
+	source, ok := FindContext(k, src)
+	if !ok {
+		return Kubeconfig{}, WithExitCode(ExitNotFound, fmt.Errorf("context %q not found", src))
+	}
+
+	if _, ok := FindContext(k, dst); ok {
+		return Kubeconfig{}, WithExitCode(ExitConflict, fmt.Errorf("context %q already exists", dst))
+	}
+
+	context := ContextEntry{Name: dst, Context: source.Context}
+	context.Context.Extensions = append([]ExtensionEntry(nil), source.Context.Extensions...)
+
+	if opts.Namespace != "" {
+		context.Context.Namespace = opts.Namespace
+	}
+
+	if opts.User != "" {
+		context.Context.User = opts.User
+	}
+
+	k.Contexts = append(append([]ContextEntry{}, k.Contexts...), context)
+
+	if opts.SetCurrent {
+		k.CurrentContext = dst
+	}
+
+	if opts.As == "" && len(opts.AsGroups) == 0 {
+		if _, ok := FindUser(k, context.Context.User); !ok && opts.User != "" {
+			return Kubeconfig{}, WithExitCode(ExitNotFound, fmt.Errorf("user %q not found", opts.User))
+		}
+
+		return k, nil
+	}
+
+	user, ok := FindUser(k, context.Context.User)
+	if !ok {
+		return Kubeconfig{}, WithExitCode(ExitNotFound, fmt.Errorf("user %q to impersonate with not found", context.Context.User))
+	}
+
+	if _, ok := FindUser(k, dst); ok {
+		return Kubeconfig{}, WithExitCode(ExitConflict, fmt.Errorf("user %q already exists", dst))
+	}
+
+	if opts.As == "" && user.User.As == "" {
+		return Kubeconfig{}, WithExitCode(ExitUsage, errAsGroupWithoutAs)
+	}
+
+	user.Name = dst
+	user.User = copyUser(user.User)
+	if opts.As != "" && opts.As != user.User.As {
+		// uid and extra belong to identity impersonated before
+		user.User.As = opts.As
+		user.User.AsUID = ""
+		user.User.AsUserExtra = nil
+	}
+	user.User.AsGroups = append([]string(nil), opts.AsGroups...)
+
+	k.Users = append(append([]UserEntry{}, k.Users...), user)
+	k.Contexts[len(k.Contexts)-1].Context.User = dst
+
+	return k, nil
+}
+
+// CloneConf is CloneContext for raw kubeconfig. New context and user are copies
+// of source ones with every field, other entries are kept as is
+func CloneConf(raw []byte, src, dst string, opts CloneOptions) ([]byte, error) {
+	config, err := ParseConf(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	expected, err := CloneContext(config, src, dst, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := parseDocument(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	// duplicate appends copy of entry of named list, renamed to dst, and returns its content
+	duplicate := func(section, name string) *yamlv3.Node {
+		entry := copyNode(findEntry(lookupMapping(d.root, section), name))
+		setMappingValue(entry, "name", stringNode(dst))
+		appendToCollection(mappingValue(d.root, section, yamlv3.SequenceNode), entry)
+
+		return mappingValue(entry, namedSections[section].key, yamlv3.MappingNode)
+	}
+
+	context := duplicate("contexts", src)
+	created, _ := FindContext(expected, dst)
+
+	if created.Context.Namespace != lookupString(context, "namespace") {
+		setMappingValue(context, "namespace", stringNode(created.Context.Namespace))
+	}
+
+	if opts.As != "" || len(opts.AsGroups) > 0 {
+		source, _ := FindContext(config, src)
+		name := source.Context.User
+		if opts.User != "" {
+			name = opts.User
+		}
+
+		user := duplicate("users", name)
+		impersonated, _ := FindUser(expected, dst)
+
+		if impersonated.User.As != lookupString(user, "as") {
+			setMappingValue(user, "as", stringNode(impersonated.User.As))
+			removeMappingKey(user, "as-uid")
+			removeMappingKey(user, "as-user-extra")
+		}
+		removeMappingKey(user, "as-groups")
+		if len(impersonated.User.AsGroups) > 0 {
+			groups := &yamlv3.Node{Kind: yamlv3.SequenceNode, Tag: "!!seq"}
+			for _, group := range impersonated.User.AsGroups {
+				groups.Content = append(groups.Content, stringNode(group))
+			}
+			setMappingValue(user, "as-groups", groups)
+		}
+	}
+
+	if created.Context.User != lookupString(context, "user") {
+		setMappingValue(context, "user", stringNode(created.Context.User))
+	}
+
+	if expected.CurrentContext != lookupString(d.root, "current-context") {
+		setMappingValue(d.root, "current-context", stringNode(expected.CurrentContext))
+	}
+
+	result, err := d.encode()
+	if err != nil {
+		return nil, err
+	}
+
+	cloned, err := ParseConf(result)
+	if err != nil {
+		return nil, err
+	}
+
+	if !reflect.DeepEqual(cloned, expected) {
+		return nil, errors.New("cloned kubeconfig differs from expected one")
+	}
+
+	return result, nil
+}
+
+// copyUser returns copy of user sharing no slices, maps or pointers with it
+func copyUser(u User) User {
+	if u.Exec != nil {
+		exec := *u.Exec
+		exec.Args = append([]string(nil), u.Exec.Args...)
+		exec.Env = append([]ExecEnvVar(nil), u.Exec.Env...)
+		u.Exec = &exec
+	}
+
+	if u.AuthProvider != nil {
+		provider := *u.AuthProvider
+		if provider.Config != nil {
+			provider.Config = map[string]string{}
+			for key, value := range u.AuthProvider.Config {
+				provider.Config[key] = value
+			}
+		}
+		u.AuthProvider = &provider
+	}
+
+	if u.AsUserExtra != nil {
+		extra := map[string][]string{}
+		for key, values := range u.AsUserExtra {
+			extra[key] = append([]string(nil), values...)
+		}
+		u.AsUserExtra = extra
+	}
+
+	u.AsGroups = append([]string(nil), u.AsGroups...)
+	u.Extensions = append([]ExtensionEntry(nil), u.Extensions...)
+
+	return u
+}
+
+// copyNode returns deep copy of yaml node without comments, so they are not duplicated
+func copyNode(node *yamlv3.Node) *yamlv3.Node {
+	copied := *node
+	copied.HeadComment, copied.LineComment, copied.FootComment = "", "", ""
+	copied.Content = nil
+
+	for _, child := range node.Content {
+		copied.Content = append(copied.Content, copyNode(child))
+	}
+
+	return &copied
+}
@@ -0,0 +1,136 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloneContext(t *testing.T) {
+	config := Kubeconfig{
+		Clusters: []ClusterEntry{{Name: "prod"}},
+		Contexts: []ContextEntry{{Name: "prod", Context: Context{Cluster: "prod", User: "admin", Namespace: "default"}}},
+		Users: []UserEntry{
+			{Name: "admin", User: User{Token: "secret"}},
+			{Name: "ci", User: User{Token: "ci"}},
+		},
+	}
+
+	result, err := CloneContext(config, "prod", "prod-payments", CloneOptions{Namespace: "payments", User: "ci"})
+	require.NoError(t, err)
+	require.Equal(t, ContextEntry{Name: "prod-payments", Context: Context{Cluster: "prod", User: "ci", Namespace: "payments"}}, result.Contexts[1])
+	require.Equal(t, config.Users, result.Users)
+	require.Len(t, config.Contexts, 1, "input must not be modified")
+
+	result, err = CloneContext(config, "prod", "prod-readonly", CloneOptions{
+		As: "system:serviceaccount:default:viewer", AsGroups: []string{"viewers"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, ContextEntry{Name: "prod-readonly", Context: Context{Cluster: "prod", User: "prod-readonly", Namespace: "default"}}, result.Contexts[1])
+	require.Equal(t, UserEntry{Name: "prod-readonly", User: User{
+		Token: "secret", As: "system:serviceaccount:default:viewer", AsGroups: []string{"viewers"},
+	}}, result.Users[2])
+	require.Len(t, config.Users, 2, "input must not be modified")
+
+	_, err = CloneContext(config, "dev", "dev-copy", CloneOptions{})
+	require.Equal(t, ExitNotFound, ExitCode(err))
+
+	_, err = CloneContext(result, "prod", "prod-readonly", CloneOptions{})
+	require.Equal(t, ExitConflict, ExitCode(err))
+
+	_, err = CloneContext(config, "prod", "admin", CloneOptions{As: "viewer"})
+	require.Equal(t, ExitConflict, ExitCode(err))
+
+	_, err = CloneContext(config, "prod", "prod-copy", CloneOptions{User: "nobody"})
+	require.Equal(t, ExitNotFound, ExitCode(err))
+
+	_, err = CloneContext(config, "prod", "prod-viewers", CloneOptions{AsGroups: []string{"viewers"}})
+	require.Equal(t, ExitUsage, ExitCode(err))
+
+	result, err = CloneContext(result, "prod-readonly", "prod-viewers", CloneOptions{AsGroups: []string{"auditors"}, SetCurrent: true})
+	require.NoError(t, err)
+	require.Equal(t, "system:serviceaccount:default:viewer", result.Users[3].User.As)
+	require.Equal(t, []string{"auditors"}, result.Users[3].User.AsGroups)
+	require.Equal(t, []string{"viewers"}, result.Users[2].User.AsGroups)
+	require.Equal(t, "prod-viewers", result.CurrentContext)
+}
+
+func TestCloneConf(t *testing.T) {
+	raw, err := CloneConf([]byte(unknownFieldsConf), "prod", "prod-readonly", CloneOptions{As: "viewer", Namespace: "default"})
+	require.NoError(t, err)
+	requireUnknownFieldsKept(t, raw)
+
+	require.Contains(t, string(raw), `- name: prod-readonly
+  context:
+    cluster: prod
+    user: prod-readonly
+    extensions:
+    - name: tool
+      extension:
+        color: red
+    namespace: default
+`)
+	require.True(t, strings.HasSuffix(string(raw), `- name: prod-readonly
+  user:
+    auth-provider:
+      name: oidc
+      config:
+        client-id: konfig
+        idp-issuer-url: https://issuer
+    as: viewer
+`))
+}
+
+func TestCloneDropsIdentityOfReplacedImpersonation(t *testing.T) {
+	raw := []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: prod
+  cluster:
+    server: https://prod
+contexts:
+- name: prod
+  context:
+    cluster: prod
+    user: impersonating
+users:
+- name: impersonating
+  user:
+    token: abc
+    as: alice
+    as-uid: "1000"
+    as-user-extra:
+      reason:
+      - audit
+`)
+	config, err := ParseConf(raw)
+	require.NoError(t, err)
+
+	result, err := CloneContext(config, "prod", "prod-bob", CloneOptions{As: "bob"})
+	require.NoError(t, err)
+	require.Equal(t, User{Token: "abc", As: "bob"}, result.Users[1].User)
+	require.Equal(t, "1000", config.Users[0].User.AsUID, "input must not be modified")
+
+	result, err = CloneContext(config, "prod", "prod-auditors", CloneOptions{AsGroups: []string{"auditors"}})
+	require.NoError(t, err)
+	require.Equal(t, "1000", result.Users[1].User.AsUID)
+	require.Equal(t, map[string][]string{"reason": {"audit"}}, result.Users[1].User.AsUserExtra)
+
+	cloned, err := CloneConf(raw, "prod", "prod-bob", CloneOptions{As: "bob"})
+	require.NoError(t, err)
+	require.True(t, strings.HasSuffix(string(cloned), `- name: prod-bob
+  user:
+    token: abc
+    as: bob
+`))
+
+	cloned, err = CloneConf(raw, "prod", "prod-alice", CloneOptions{As: "alice", AsGroups: []string{"auditors"}})
+	require.NoError(t, err)
+	require.Contains(t, string(cloned), `- name: prod-alice
+  user:
+    token: abc
+    as: alice
+    as-uid: "1000"
+`)
+}
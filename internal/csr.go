@@ -0,0 +1,234 @@
+package internal
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Key types supported by GenerateKey
+const (
+	KeyTypeECDSA = "ecdsa"
+	KeyTypeRSA   = "rsa"
+)
+
+// rsaKeyBits is size of generated RSA keys
+const rsaKeyBits = 2048
+
+// KubeAPIServerClientSigner is signer issuing client certificates trusted by API server
+const KubeAPIServerClientSigner = "kubernetes.io/kube-apiserver-client"
+
+// csrPath is API path of CertificateSigningRequests
+const csrPath = "/apis/certificates.k8s.io/v1/certificatesigningrequests"
+
+// MinCSRExpiration is the shortest certificate lifetime API server accepts
+const MinCSRExpiration = 10 * time.Minute
+
+// MaxCSRExpiration is the longest certificate lifetime expirationSeconds can hold
+const MaxCSRExpiration = math.MaxInt32 * time.Second
+
+// maxCSRNamePrefix limits part of CertificateSigningRequest name taken from user name
+const maxCSRNamePrefix = 200
+
+// invalidObjectNameChars matches characters which are not allowed in names of API objects
+var invalidObjectNameChars = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// CSRCondition is condition of CertificateSigningRequest
+type CSRCondition struct {
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// CertificateSigningRequest is certificates.k8s.io/v1 CertificateSigningRequest object
+type CertificateSigningRequest struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Metadata   struct {
+		Name            string `json:"name"`
+		ResourceVersion string `json:"resourceVersion,omitempty"`
+	} `json:"metadata"`
+	Spec struct {
+		Request           []byte   `json:"request"`
+		SignerName        string   `json:"signerName"`
+		ExpirationSeconds *int32   `json:"expirationSeconds,omitempty"`
+		Usages            []string `json:"usages"`
+	} `json:"spec"`
+	Status struct {
+		Conditions  []CSRCondition `json:"conditions,omitempty"`
+		Certificate []byte         `json:"certificate,omitempty"`
+	} `json:"status"`
+}
+
+// GenerateKey generates private key of given type and returns it with its PEM form
+func GenerateKey(keyType string) (crypto.Signer, []byte, error) {
+	var key crypto.Signer
+	var err error
+
+	switch keyType {
+	case KeyTypeECDSA:
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case KeyTypeRSA:
+		key, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	default:
+		return nil, nil, WithExitCode(ExitUsage, fmt.Errorf("unknown key type %q, use %s or %s", keyType, KeyTypeECDSA, KeyTypeRSA))
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return key, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// CreateCSR returns PEM encoded certificate request for user name in groups,
+// which API server takes from common name and organizations
+func CreateCSR(key crypto.Signer, name string, groups []string) ([]byte, error) {
+	template := &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: name, Organization: groups},
+	}
+
+	der, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}), nil
+}
+
+// ValidateCSRExpiration checks that requested certificate lifetime is accepted by API server
+func ValidateCSRExpiration(expiration time.Duration) error {
+	if expiration < MinCSRExpiration || expiration > MaxCSRExpiration {
+		return WithExitCode(ExitUsage, fmt.Errorf(
+			"expiration must be between %s and %s, got %s", MinCSRExpiration, MaxCSRExpiration, expiration,
+		))
+	}
+
+	return nil
+}
+
+// CSRName returns name of CertificateSigningRequest for user with random suffix,
+// so it does not clash with requests left by previous attempts
+func CSRName(user string) (string, error) {
+	suffix := make([]byte, 4)
+	_, err := rand.Read(suffix)
+	if err != nil {
+		return "", err
+	}
+
+	name := strings.Trim(invalidObjectNameChars.ReplaceAllString(strings.ToLower(user), "-"), "-.")
+	if len(name) > maxCSRNamePrefix {
+		name = strings.TrimRight(name[:maxCSRNamePrefix], "-.")
+	}
+	if name == "" {
+		name = "user"
+	}
+
+	return fmt.Sprintf("%s-%x", name, suffix), nil
+}
+
+// RequestCertificate submits CertificateSigningRequest name, approves it if approve
+// is set and waits up to timeout for it to be signed, returning PEM encoded certificate
+func RequestCertificate(client *APIClient, name string, csr []byte, expiration time.Duration, approve bool, timeout, interval time.Duration) ([]byte, error) {
+	err := SubmitCSR(client, name, csr, expiration)
+	if err != nil {
+		return nil, err
+	}
+
+	if approve {
+		err = ApproveCSR(client, name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return WaitForCertificate(client, name, timeout, interval)
+}
+
+// SubmitCSR creates CertificateSigningRequest name for client certificate. Zero
+// expiration leaves certificate lifetime to signer
+func SubmitCSR(client *APIClient, name string, csr []byte, expiration time.Duration) error {
+	request := CertificateSigningRequest{APIVersion: "certificates.k8s.io/v1", Kind: "CertificateSigningRequest"}
+	request.Metadata.Name = name
+	request.Spec.Request = csr
+	request.Spec.SignerName = KubeAPIServerClientSigner
+	request.Spec.Usages = []string{"client auth"}
+
+	if expiration > 0 {
+		seconds := int32(expiration / time.Second)
+		request.Spec.ExpirationSeconds = &seconds
+	}
+
+	return client.Do(http.MethodPost, csrPath, request, nil)
+}
+
+// ApproveCSR approves CertificateSigningRequest name, like 'kubectl certificate approve' does
+func ApproveCSR(client *APIClient, name string) error {
+	request := CertificateSigningRequest{}
+
+	err := client.Do(http.MethodGet, csrPath+"/"+name, nil, &request)
+	if err != nil {
+		return err
+	}
+
+	request.Status.Conditions = append(request.Status.Conditions, CSRCondition{
+		Type:    "Approved",
+		Status:  "True",
+		Reason:  "KonfigApprove",
+		Message: "approved by konfig user new",
+	})
+
+	return client.Do(http.MethodPut, csrPath+"/"+name+"/approval", request, nil)
+}
+
+// WaitForCertificate polls CertificateSigningRequest name every interval until
+// it is signed and returns PEM encoded certificate. It fails if request is
+// denied, signing fails or timeout expires
+func WaitForCertificate(client *APIClient, name string, timeout, interval time.Duration) ([]byte, error) {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		request := CertificateSigningRequest{}
+
+		err := client.Do(http.MethodGet, csrPath+"/"+name, nil, &request)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, condition := range request.Status.Conditions {
+			if (condition.Type == "Denied" || condition.Type == "Failed") && condition.Status == "True" {
+				return nil, fmt.Errorf("certificate signing request %s is %s: %s",
+					name, condition.Type, condition.Message)
+			}
+		}
+
+		if len(request.Status.Certificate) > 0 {
+			return request.Status.Certificate, nil
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf(
+				"certificate signing request %s is not signed in %s, approve it with 'kubectl certificate approve %s'",
+				name, timeout, name,
+			)
+		}
+
+		time.Sleep(interval)
+	}
+}
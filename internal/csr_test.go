@@ -0,0 +1,315 @@
+package internal
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCSRServer is API server storing CertificateSigningRequests in memory and
+// signing approved ones with its own CA. Handler errors are answered with 500
+// and recorded, so tests check them instead of failing in handler goroutine
+type fakeCSRServer struct {
+	mu       sync.Mutex
+	requests map[string]*CertificateSigningRequest
+	errs     []error
+}
+
+// errors returns errors met by handler so far
+func (s *fakeCSRServer) errors() []error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]error(nil), s.errs...)
+}
+
+func (s *fakeCSRServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fail := func(code int, message string) {
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(apiStatus{Message: message})
+	}
+
+	internalError := func(err error) {
+		s.errs = append(s.errs, err)
+		fail(http.StatusInternalServerError, err.Error())
+	}
+
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		fail(http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	name := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, csrPath), "/")
+	approval := strings.HasSuffix(name, "/approval")
+	name = strings.TrimSuffix(name, "/approval")
+
+	request := CertificateSigningRequest{}
+	if r.Body != nil && r.Method != http.MethodGet {
+		err := json.NewDecoder(r.Body).Decode(&request)
+		if err != nil {
+			internalError(err)
+			return
+		}
+	}
+
+	switch {
+	case r.Method == http.MethodPost && name == "":
+		if _, ok := s.requests[request.Metadata.Name]; ok {
+			fail(http.StatusConflict, "already exists")
+			return
+		}
+		s.requests[request.Metadata.Name] = &request
+	case r.Method == http.MethodGet && s.requests[name] != nil:
+		request = *s.requests[name]
+	case r.Method == http.MethodPut && approval && s.requests[name] != nil:
+		stored := s.requests[name]
+		stored.Status.Conditions = request.Status.Conditions
+		cert, err := s.sign(stored.Spec.Request)
+		if err != nil {
+			internalError(err)
+			return
+		}
+		stored.Status.Certificate = cert
+		request = *stored
+	default:
+		fail(http.StatusNotFound, "not found")
+		return
+	}
+
+	err := json.NewEncoder(w).Encode(request)
+	if err != nil {
+		s.errs = append(s.errs, err)
+	}
+}
+
+// sign issues certificate for PEM encoded request, signed by throwaway CA
+func (s *fakeCSRServer) sign(csr []byte) ([]byte, error) {
+	block, _ := pem.Decode(csr)
+	if block == nil {
+		return nil, errors.New("request is not PEM encoded")
+	}
+
+	request, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	err = request.CheckSignature()
+	if err != nil {
+		return nil, err
+	}
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      request.Subject,
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	parent := &x509.Certificate{SerialNumber: big.NewInt(2), Subject: pkix.Name{CommonName: "ca"}}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, request.PublicKey, caKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), nil
+}
+
+func newFakeCSRClient(t *testing.T, token string) (*APIClient, *fakeCSRServer) {
+	fake := &fakeCSRServer{requests: map[string]*CertificateSigningRequest{}}
+
+	server := httptest.NewTLSServer(fake)
+	t.Cleanup(server.Close)
+
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	config := SingleContextConf("fake", Cluster{
+		Server:                   server.URL,
+		CertificateAuthorityData: base64.StdEncoding.EncodeToString(ca),
+	}, User{Token: token}, "")
+
+	client, err := NewAPIClient(config, "", t.TempDir())
+	require.NoError(t, err)
+
+	return client, fake
+}
+
+func TestCreateCSR(t *testing.T) {
+	for _, keyType := range []string{KeyTypeECDSA, KeyTypeRSA} {
+		key, keyPEM, err := GenerateKey(keyType)
+		require.NoError(t, err)
+
+		block, _ := pem.Decode(keyPEM)
+		require.Equal(t, "PRIVATE KEY", block.Type)
+
+		csr, err := CreateCSR(key, "alice", []string{"dev", "ops"})
+		require.NoError(t, err)
+
+		block, _ = pem.Decode(csr)
+		require.Equal(t, "CERTIFICATE REQUEST", block.Type)
+
+		request, err := x509.ParseCertificateRequest(block.Bytes)
+		require.NoError(t, err)
+		require.Equal(t, "alice", request.Subject.CommonName)
+		require.Equal(t, []string{"dev", "ops"}, request.Subject.Organization)
+	}
+
+	_, _, err := GenerateKey("dsa")
+	require.Equal(t, ExitUsage, ExitCode(err))
+}
+
+func TestSubmitCSR(t *testing.T) {
+	client, fake := newFakeCSRClient(t, "secret")
+
+	key, _, err := GenerateKey(KeyTypeECDSA)
+	require.NoError(t, err)
+
+	csr, err := CreateCSR(key, "alice", []string{"dev"})
+	require.NoError(t, err)
+
+	require.NoError(t, SubmitCSR(client, "alice", csr, time.Hour))
+	require.Equal(t, ExitConflict, ExitCode(SubmitCSR(client, "alice", csr, 0)))
+
+	submitted := fake.requests["alice"]
+	require.Equal(t, csr, submitted.Spec.Request)
+	require.Equal(t, KubeAPIServerClientSigner, submitted.Spec.SignerName)
+	require.Equal(t, []string{"client auth"}, submitted.Spec.Usages)
+	require.Equal(t, int32(3600), *submitted.Spec.ExpirationSeconds)
+
+	_, err = WaitForCertificate(client, "alice", 0, time.Millisecond)
+	require.ErrorContains(t, err, "kubectl certificate approve alice")
+
+	require.NoError(t, ApproveCSR(client, "alice"))
+	require.Equal(t, "Approved", fake.requests["alice"].Status.Conditions[0].Type)
+
+	cert, err := WaitForCertificate(client, "alice", time.Second, time.Millisecond)
+	require.NoError(t, err)
+
+	block, _ := pem.Decode(cert)
+	parsed, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	require.Equal(t, "alice", parsed.Subject.CommonName)
+
+	require.Equal(t, ExitNotFound, ExitCode(ApproveCSR(client, "bob")))
+
+	fake.requests["bob"] = &CertificateSigningRequest{}
+	fake.requests["bob"].Status.Conditions = []CSRCondition{{Type: "Denied", Status: "True", Message: "no"}}
+
+	_, err = WaitForCertificate(client, "bob", time.Second, time.Millisecond)
+	require.ErrorContains(t, err, "bob is Denied: no")
+	require.Empty(t, fake.errors())
+}
+
+func TestValidateCSRExpiration(t *testing.T) {
+	require.NoError(t, ValidateCSRExpiration(MinCSRExpiration))
+	require.NoError(t, ValidateCSRExpiration(365*24*time.Hour))
+	require.NoError(t, ValidateCSRExpiration(MaxCSRExpiration))
+
+	for _, expiration := range []time.Duration{-time.Hour, 0, time.Minute, MaxCSRExpiration + time.Second, 100 * 365 * 24 * time.Hour} {
+		require.Equal(t, ExitUsage, ExitCode(ValidateCSRExpiration(expiration)), expiration)
+	}
+}
+
+func TestCSRName(t *testing.T) {
+	first, err := CSRName("Alice@Example.com")
+	require.NoError(t, err)
+	require.Regexp(t, `^alice-example.com-[0-9a-f]{8}$`, first)
+
+	second, err := CSRName("Alice@Example.com")
+	require.NoError(t, err)
+	require.NotEqual(t, first, second)
+
+	name, err := CSRName("system:admin")
+	require.NoError(t, err)
+	require.Regexp(t, `^system-admin-[0-9a-f]{8}$`, name)
+
+	name, err = CSRName("::")
+	require.NoError(t, err)
+	require.Regexp(t, `^user-[0-9a-f]{8}$`, name)
+
+	name, err = CSRName(strings.Repeat("a", 300))
+	require.NoError(t, err)
+	require.LessOrEqual(t, len(name), 253)
+}
+
+func TestRequestCertificate(t *testing.T) {
+	client, fake := newFakeCSRClient(t, "secret")
+
+	key, _, err := GenerateKey(KeyTypeECDSA)
+	require.NoError(t, err)
+
+	csr, err := CreateCSR(key, "alice", nil)
+	require.NoError(t, err)
+
+	// request left by earlier attempt does not get in the way
+	fake.requests["alice"] = &CertificateSigningRequest{}
+
+	name, err := CSRName("alice")
+	require.NoError(t, err)
+
+	cert, err := RequestCertificate(client, name, csr, 0, true, time.Second, time.Millisecond)
+	require.NoError(t, err)
+	require.Contains(t, string(cert), "BEGIN CERTIFICATE")
+	require.Nil(t, fake.requests[name].Spec.ExpirationSeconds)
+
+	_, err = RequestCertificate(client, name, csr, 0, true, time.Second, time.Millisecond)
+	require.Equal(t, ExitConflict, ExitCode(err))
+
+	other, err := CSRName("alice")
+	require.NoError(t, err)
+
+	_, err = RequestCertificate(client, other, []byte("garbage"), 0, true, time.Second, time.Millisecond)
+	require.ErrorContains(t, err, "500")
+	require.Len(t, fake.errors(), 1)
+}
+
+func TestNewAPIClient(t *testing.T) {
+	client, _ := newFakeCSRClient(t, "wrong")
+
+	err := client.Do(http.MethodGet, csrPath+"/alice", nil, nil)
+	require.ErrorContains(t, err, "401 Unauthorized: Unauthorized")
+
+	config := SingleContextConf("exec", Cluster{Server: "https://localhost"}, User{
+		Exec: &ExecConfig{Command: "aws"},
+	}, "")
+
+	_, err = NewAPIClient(config, "", t.TempDir())
+	require.Equal(t, ExitUsage, ExitCode(err))
+
+	for _, user := range []User{{AuthProvider: &AuthProviderConfig{Name: "oidc"}}, {}} {
+		config = SingleContextConf("other", Cluster{Server: "https://localhost"}, user, "")
+
+		_, err = NewAPIClient(config, "", t.TempDir())
+		require.Equal(t, ExitUsage, ExitCode(err))
+		require.ErrorContains(t, err, "has no token, basic auth or client certificate")
+	}
+
+	config = SingleContextConf("basic", Cluster{Server: "https://localhost"}, User{Username: "admin", Password: "secret"}, "")
+
+	_, err = NewAPIClient(config, "", t.TempDir())
+	require.NoError(t, err)
+}
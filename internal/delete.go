@@ -0,0 +1,127 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// RemoveContexts returns copy of kubeconfig without contexts with given names.
+// Unless keepOrphans is set, clusters and users which are no longer referenced
+// by any context are removed as well. If current context is removed, current-context is cleared
+func RemoveContexts(k Kubeconfig, names []string, keepOrphans bool) Kubeconfig {
+	remove := map[string]bool{}
+	for _, name := range names {
+		remove[name] = true
+	}
+
+	contexts := []ContextEntry{}
+	for _, entry := range k.Contexts {
+		if !remove[entry.Name] {
+			contexts = append(contexts, entry)
+		}
+	}
+
+	usedClusters := map[string]bool{}
+	usedUsers := map[string]bool{}
+	for _, entry := range contexts {
+		usedClusters[entry.Context.Cluster] = true
+		usedUsers[entry.Context.User] = true
+	}
+
+	removedClusters := map[string]bool{}
+	removedUsers := map[string]bool{}
+	for _, entry := range k.Contexts {
+		if remove[entry.Name] && !keepOrphans {
+			removedClusters[entry.Context.Cluster] = !usedClusters[entry.Context.Cluster]
+			removedUsers[entry.Context.User] = !usedUsers[entry.Context.User]
+		}
+	}
+
+	clusters := []ClusterEntry{}
+	for _, entry := range k.Clusters {
+		if !removedClusters[entry.Name] {
+			clusters = append(clusters, entry)
+		}
+	}
+
+	users := []UserEntry{}
+	for _, entry := range k.Users {
+		if !removedUsers[entry.Name] {
+			users = append(users, entry)
+		}
+	}
+
+	k.Contexts = contexts
+	k.Clusters = clusters
+	k.Users = users
+
+	if remove[k.CurrentContext] {
+		k.CurrentContext = ""
+	}
+
+	return k
+}
+
+// MatchNames returns names matching any of patterns, keeping order of names.
+// Patterns are shell globs, where * and ? match any characters including /,
+// or regular expressions if regex is set. Both must match whole name, so prod
+// does not match preprod-eu. Every pattern must match something
+func MatchNames(names, patterns []string, regex bool) ([]string, error) {
+	matched := map[string]bool{}
+
+	for _, pattern := range patterns {
+		expr := "^(?:" + pattern + ")$"
+		if !regex {
+			expr = globToRegexp(pattern)
+		}
+
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, WithExitCode(ExitUsage, fmt.Errorf("invalid pattern %q: %w", pattern, err))
+		}
+
+		found := false
+		for _, name := range names {
+			if re.MatchString(name) {
+				matched[name] = true
+				found = true
+			}
+		}
+
+		if !found {
+			return nil, WithExitCode(ExitNotFound, fmt.Errorf("nothing matches %q", pattern))
+		}
+	}
+
+	result := []string{}
+	for _, name := range names {
+		if matched[name] {
+			result = append(result, name)
+			delete(matched, name)
+		}
+	}
+
+	return result, nil
+}
+
+// globToRegexp turns glob into anchored regular expression. Unlike path.Match,
+// * matches / too, since context names like EKS ARNs often contain it
+func globToRegexp(glob string) string {
+	var expr strings.Builder
+	expr.WriteString("^")
+
+	for _, char := range glob {
+		switch char {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(char)))
+		}
+	}
+
+	expr.WriteString("$")
+	return expr.String()
+}
@@ -0,0 +1,98 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoveContexts(t *testing.T) {
+	config := Kubeconfig{
+		Clusters: []ClusterEntry{{Name: "shared"}, {Name: "prod"}, {Name: "orphan"}},
+		Contexts: []ContextEntry{
+			{Name: "prod", Context: Context{Cluster: "prod", User: "prod"}},
+			{Name: "prod-shared", Context: Context{Cluster: "shared", User: "prod"}},
+			{Name: "dev", Context: Context{Cluster: "shared", User: "dev"}},
+		},
+		Users:          []UserEntry{{Name: "prod"}, {Name: "dev"}},
+		CurrentContext: "prod",
+	}
+
+	result := RemoveContexts(config, []string{"prod", "prod-shared"}, false)
+	require.Equal(t, []ContextEntry{config.Contexts[2]}, result.Contexts)
+	require.Equal(t, []ClusterEntry{{Name: "shared"}, {Name: "orphan"}}, result.Clusters)
+	require.Equal(t, []UserEntry{{Name: "dev"}}, result.Users)
+	require.Empty(t, result.CurrentContext)
+	require.Len(t, config.Contexts, 3, "input must not be modified")
+
+	result = RemoveContexts(config, []string{"prod", "prod-shared"}, true)
+	require.Equal(t, []ContextEntry{config.Contexts[2]}, result.Contexts)
+	require.Equal(t, config.Clusters, result.Clusters)
+	require.Equal(t, config.Users, result.Users)
+	require.Empty(t, result.CurrentContext)
+}
+
+func TestApplyChangesKeepsUnknownFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(path, []byte(unknownFieldsConf), 0600))
+
+	cmd := &cobra.Command{}
+	cmd.Flags().Bool(OptionYes, true, "")
+	cmd.Flags().Bool(OptionDryRun, false, "")
+	cmd.Flags().Duration(OptionLockTimeout, time.Second, "")
+
+	require.NoError(t, ApplyChanges(cmd, path, func(k Kubeconfig) (Kubeconfig, error) {
+		return RemoveContexts(k, []string{"dev"}, false), nil
+	}))
+
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	requireUnknownFieldsKept(t, raw)
+
+	config, err := ReadConf(path)
+	require.NoError(t, err)
+	require.Equal(t, []ContextEntry{{Name: "prod", Context: Context{Cluster: "prod", User: "oidc", Extensions: []ExtensionEntry{{Name: "tool"}}}}}, config.Contexts)
+}
+
+func TestMatchNames(t *testing.T) {
+	names := []string{"arn:aws:eks:eu-west-1:123:cluster/prod", "arn:aws:eks:eu-west-1:123:cluster/dev", "kind-dev", "minikube"}
+
+	matched, err := MatchNames(names, []string{"minikube", "arn:aws:eks:*"}, false)
+	require.NoError(t, err)
+	require.Equal(t, []string{names[0], names[1], names[3]}, matched)
+
+	matched, err = MatchNames(names, []string{"*dev", "kind-?ev"}, false)
+	require.NoError(t, err)
+	require.Equal(t, []string{names[1], names[2]}, matched)
+
+	matched, err = MatchNames(names, []string{"kind-.*", "mini.*"}, true)
+	require.NoError(t, err)
+	require.Equal(t, []string{names[2], names[3]}, matched)
+
+	// regular expressions are anchored, so partial matches are not deleted
+	matched, err = MatchNames([]string{"prod", "preprod-eu", "prod-eu"}, []string{"prod|dev"}, true)
+	require.NoError(t, err)
+	require.Equal(t, []string{"prod"}, matched)
+
+	_, err = MatchNames(names, []string{"mini"}, true)
+	require.Equal(t, ExitNotFound, ExitCode(err))
+
+	_, err = MatchNames(names, []string{"kind"}, false)
+	require.Equal(t, ExitNotFound, ExitCode(err))
+
+	_, err = MatchNames(names, []string{"("}, true)
+	require.Equal(t, ExitUsage, ExitCode(err))
+}
+
+func TestConfirm(t *testing.T) {
+	for answer, expected := range map[string]bool{"y\n": true, "YES\n": true, "n\n": false, "\n": false, "": false, "yes": true} {
+		ok, err := Confirm(strings.NewReader(answer), "proceed?")
+		require.NoError(t, err)
+		require.Equal(t, expected, ok, "answer %q", answer)
+	}
+}
@@ -0,0 +1,296 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"gopkg.in/yaml.v2"
+)
+
+// ChangeKind is kind of change of kubeconfig entry
+type ChangeKind string
+
+const (
+	// ChangeAdded means entry exists only in new kubeconfig
+	ChangeAdded ChangeKind = "added"
+	// ChangeRemoved means entry exists only in old kubeconfig
+	ChangeRemoved ChangeKind = "removed"
+	// ChangeModified means entry exists in both kubeconfigs, but differs
+	ChangeModified ChangeKind = "modified"
+)
+
+// FieldChange represents change of single field of kubeconfig entry
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old,omitempty"`
+	New   string `json:"new,omitempty"`
+}
+
+// EntryChange represents change of cluster, context or user entry
+// or change of top-level setting like current-context
+type EntryChange struct {
+	Section string        `json:"section"`
+	Name    string        `json:"name,omitempty"`
+	Kind    ChangeKind    `json:"kind"`
+	Fields  []FieldChange `json:"fields,omitempty"`
+}
+
+// Diff compares kubeconfigs, matching clusters, contexts and users by name
+func Diff(before, after Kubeconfig) ([]EntryChange, error) {
+	changes := []EntryChange{}
+
+	if before.CurrentContext != after.CurrentContext {
+		changes = append(changes, EntryChange{
+			Section: "current-context",
+			Kind:    ChangeModified,
+			Fields:  []FieldChange{{Field: "current-context", Old: before.CurrentContext, New: after.CurrentContext}},
+		})
+	}
+
+	sections := []struct {
+		name          string
+		before, after map[string]interface{}
+	}{
+		{"cluster", clustersByName(before), clustersByName(after)},
+		{"context", contextsByName(before), contextsByName(after)},
+		{"user", usersByName(before), usersByName(after)},
+	}
+
+	for _, section := range sections {
+		sectionChanges, err := diffSection(section.name, section.before, section.after)
+		if err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, sectionChanges...)
+	}
+
+	return changes, nil
+}
+
+func clustersByName(k Kubeconfig) map[string]interface{} {
+	entries := map[string]interface{}{}
+	for i := len(k.Clusters) - 1; i >= 0; i-- {
+		entries[k.Clusters[i].Name] = k.Clusters[i].Cluster
+	}
+
+	return entries
+}
+
+func contextsByName(k Kubeconfig) map[string]interface{} {
+	entries := map[string]interface{}{}
+	for i := len(k.Contexts) - 1; i >= 0; i-- {
+		entries[k.Contexts[i].Name] = k.Contexts[i].Context
+	}
+
+	return entries
+}
+
+func usersByName(k Kubeconfig) map[string]interface{} {
+	entries := map[string]interface{}{}
+	for i := len(k.Users) - 1; i >= 0; i-- {
+		entries[k.Users[i].Name] = k.Users[i].User
+	}
+
+	return entries
+}
+
+func diffSection(section string, before, after map[string]interface{}) ([]EntryChange, error) {
+	names := []string{}
+	for name := range before {
+		names = append(names, name)
+	}
+	for name := range after {
+		if _, ok := before[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	changes := []EntryChange{}
+	for _, name := range names {
+		oldEntry, inOld := before[name]
+		newEntry, inNew := after[name]
+
+		switch {
+		case !inOld:
+			changes = append(changes, EntryChange{Section: section, Name: name, Kind: ChangeAdded})
+		case !inNew:
+			changes = append(changes, EntryChange{Section: section, Name: name, Kind: ChangeRemoved})
+		default:
+			fields, err := diffFields(oldEntry, newEntry)
+			if err != nil {
+				return nil, err
+			}
+
+			if len(fields) > 0 {
+				changes = append(changes, EntryChange{Section: section, Name: name, Kind: ChangeModified, Fields: fields})
+			}
+		}
+	}
+
+	return changes, nil
+}
+
+func diffFields(before, after interface{}) ([]FieldChange, error) {
+	oldFields, err := flattenFields(before)
+	if err != nil {
+		return nil, err
+	}
+
+	newFields, err := flattenFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for name := range oldFields {
+		names = append(names, name)
+	}
+	for name := range newFields {
+		if _, ok := oldFields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	changes := []FieldChange{}
+	for _, name := range names {
+		if oldFields[name] != newFields[name] {
+			changes = append(changes, FieldChange{Field: name, Old: oldFields[name], New: newFields[name]})
+		}
+	}
+
+	return changes, nil
+}
+
+// flattenFields returns non-empty fields of entry keyed by their dotted path.
+// Secrets are replaced with fingerprints, so they can be compared but not read
+func flattenFields(entry interface{}) (map[string]string, error) {
+	raw, err := yaml.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc interface{}
+	err = yaml.Unmarshal(raw, &doc)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]string{}
+	flattenValue("", doc, fields)
+
+	return fields, nil
+}
+
+func flattenValue(path string, value interface{}, fields map[string]string) {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		for key, item := range v {
+			name := fmt.Sprintf("%v", key)
+			if path != "" {
+				name = path + "." + name
+			}
+			flattenValue(name, item, fields)
+		}
+	case []interface{}:
+		for i, item := range v {
+			flattenValue(fmt.Sprintf("%s[%d]", path, i), item, fields)
+		}
+	case nil:
+	default:
+		s := fmt.Sprintf("%v", v)
+		if s == "" {
+			return
+		}
+
+		if IsSecretField(path) {
+			s = secretFingerprint(path, s)
+		}
+
+		fields[path] = s
+	}
+}
+
+// IsSecretField reports whether field at dotted path holds credentials or
+// certificate data which should not be shown to users as is
+func IsSecretField(path string) bool {
+	name := path[strings.LastIndex(path, ".")+1:]
+
+	return strings.HasSuffix(name, "-data") || strings.HasSuffix(name, "token") ||
+		strings.HasSuffix(name, "secret") || name == "password"
+}
+
+// secretFingerprint returns fingerprint of secret field value. Data fields are
+// decoded first, and certificates get the same fingerprints konfig certs shows
+func secretFingerprint(path, value string) string {
+	if !strings.HasSuffix(path, "-data") {
+		return Fingerprint([]byte(value))
+	}
+
+	content, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return Fingerprint([]byte(value))
+	}
+
+	certs, err := ParseCertificates(content)
+	if err != nil {
+		return Fingerprint(content)
+	}
+
+	fingerprints := []string{}
+	for _, cert := range certs {
+		fingerprints = append(fingerprints, CertificateFingerprint(cert))
+	}
+
+	return strings.Join(fingerprints, ", ")
+}
+
+// Fingerprint returns short sha256 fingerprint of data
+func Fingerprint(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
+
+// PrintDiff prints changes in human readable form with colors
+func PrintDiff(changes []EntryChange) {
+	green := color.New(color.FgGreen)
+	red := color.New(color.FgRed)
+	yellow := color.New(color.FgYellow)
+
+	for _, change := range changes {
+		switch change.Kind {
+		case ChangeAdded:
+			green.Printf("+ %s %s\n", change.Section, change.Name)
+		case ChangeRemoved:
+			red.Printf("- %s %s\n", change.Section, change.Name)
+		case ChangeModified:
+			if change.Name == "" {
+				yellow.Printf("~ %s\n", change.Section)
+			} else {
+				yellow.Printf("~ %s %s\n", change.Section, change.Name)
+			}
+
+			for _, field := range change.Fields {
+				fmt.Printf("    %s: ", field.Field)
+				red.Print(valueOrNone(field.Old))
+				fmt.Print(" -> ")
+				green.Println(valueOrNone(field.New))
+			}
+		}
+	}
+}
+
+func valueOrNone(value string) string {
+	if value == "" {
+		return "<none>"
+	}
+
+	return value
+}
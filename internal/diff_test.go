@@ -0,0 +1,87 @@
+package internal
+
+import (
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	before := Kubeconfig{
+		Clusters: []ClusterEntry{
+			{Name: "a", Cluster: Cluster{Server: "https://a", CertificateAuthorityData: "QUJD"}},
+			{Name: "b", Cluster: Cluster{Server: "https://b"}},
+		},
+		Contexts: []ContextEntry{
+			{Name: "a", Context: Context{Cluster: "a", User: "u"}},
+		},
+		Users: []UserEntry{
+			{Name: "u", User: User{Token: "secret"}},
+		},
+		CurrentContext: "a",
+	}
+
+	after := Kubeconfig{
+		Clusters: []ClusterEntry{
+			{Name: "c", Cluster: Cluster{Server: "https://c"}},
+			{Name: "a", Cluster: Cluster{Server: "https://a2", CertificateAuthorityData: "QUJD"}},
+		},
+		Contexts: []ContextEntry{
+			{Name: "a", Context: Context{Cluster: "a", User: "u"}},
+		},
+		Users: []UserEntry{
+			{Name: "u", User: User{Token: "rotated"}},
+		},
+		CurrentContext: "c",
+	}
+
+	changes, err := Diff(before, after)
+	require.NoError(t, err)
+	require.Equal(t, []EntryChange{
+		{
+			Section: "current-context",
+			Kind:    ChangeModified,
+			Fields:  []FieldChange{{Field: "current-context", Old: "a", New: "c"}},
+		},
+		{
+			Section: "cluster",
+			Name:    "a",
+			Kind:    ChangeModified,
+			Fields:  []FieldChange{{Field: "server", Old: "https://a", New: "https://a2"}},
+		},
+		{Section: "cluster", Name: "b", Kind: ChangeRemoved},
+		{Section: "cluster", Name: "c", Kind: ChangeAdded},
+		{
+			Section: "user",
+			Name:    "u",
+			Kind:    ChangeModified,
+			Fields: []FieldChange{{
+				Field: "token",
+				Old:   Fingerprint([]byte("secret")),
+				New:   Fingerprint([]byte("rotated")),
+			}},
+		},
+	}, changes)
+
+	changes, err = Diff(before, before)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+}
+
+func TestSecretFingerprint(t *testing.T) {
+	cert, key := testCertificate(t, pkix.Name{CommonName: "admin"}, time.Now().Add(time.Hour))
+
+	infos := InspectCertificates(Kubeconfig{Users: []UserEntry{{Name: "admin", User: User{
+		ClientCertificateData: base64.StdEncoding.EncodeToString(cert),
+	}}}}, "")
+	require.Len(t, infos, 1)
+
+	require.Equal(t, infos[0].Fingerprint,
+		secretFingerprint("client-certificate-data", base64.StdEncoding.EncodeToString(cert)))
+	require.Equal(t, Fingerprint(key),
+		secretFingerprint("client-key-data", base64.StdEncoding.EncodeToString(key)))
+	require.Equal(t, Fingerprint([]byte("secret")), secretFingerprint("token", "secret"))
+}
@@ -0,0 +1,595 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// listItemPrefix matches text in front of block sequence item on its first line
+var listItemPrefix = regexp.MustCompile(`^ *- +$`)
+
+// document is raw kubeconfig parsed into yaml nodes, which can be edited and
+// encoded back. Top-level keys and entries of lists which were not changed are
+// copied from original text byte for byte, so comments, blank lines and quoting
+// survive. Changed parts are encoded with indentation of original text
+type document struct {
+	doc  yamlv3.Node
+	root *yamlv3.Node
+
+	lines        []string
+	indent       int
+	compactLists bool
+
+	// spans are locations of top-level keys and list items in original text,
+	// keyed by key and item nodes, original are their encodings at parse time
+	spans    map[*yamlv3.Node]span
+	original map[*yamlv3.Node]string
+	// lists are original block sequences of top-level keys, whose items have spans
+	lists map[*yamlv3.Node]list
+	// prefix is number of lines before the first top-level key
+	prefix int
+}
+
+// span is location of node in lines of original text: comments and blank lines
+// above node are [start, body), node itself is [body, tail) and trailing comments
+// and blank lines of the last node of a block are [tail, end)
+type span struct {
+	start, body, tail, end int
+}
+
+// list is block sequence which is value of top-level key. Its items are written
+// with dash at column, after lines of key which end before line first
+type list struct {
+	node   *yamlv3.Node
+	column int
+	first  int
+}
+
+// parseDocument parses raw kubeconfig. Empty content gives document with
+// apiVersion and kind set, like kubectl creates
+func parseDocument(raw []byte) (*document, error) {
+	d := &document{
+		spans:    map[*yamlv3.Node]span{},
+		original: map[*yamlv3.Node]string{},
+		lists:    map[*yamlv3.Node]list{},
+	}
+
+	err := yamlv3.Unmarshal(raw, &d.doc)
+	if err != nil {
+		return nil, WithExitCode(ExitValidation, fmt.Errorf("cannot parse kubeconfig: %w", err))
+	}
+
+	if len(d.doc.Content) == 0 {
+		root := newMapping()
+		setMappingValue(root, "apiVersion", stringNode("v1"))
+		setMappingValue(root, "kind", stringNode("Config"))
+		d.doc = yamlv3.Node{Kind: yamlv3.DocumentNode, Content: []*yamlv3.Node{root}}
+	}
+
+	d.root = d.doc.Content[0]
+	if d.root.Kind != yamlv3.MappingNode {
+		return nil, WithExitCode(ExitValidation, errors.New("kubeconfig is not a yaml mapping"))
+	}
+
+	d.indent, d.compactLists = detectIndent(d.root)
+
+	if len(raw) > 0 {
+		d.lines = strings.SplitAfter(string(raw), "\n")
+		d.locate()
+	}
+
+	return d, nil
+}
+
+// locate finds spans of top-level keys and items of their block sequences.
+// Documents which are not plain block mappings get no spans and are encoded as a whole
+func (d *document) locate() {
+	if d.root.Style&yamlv3.FlowStyle != 0 {
+		return
+	}
+
+	keys := []*yamlv3.Node{}
+	starts := []int{}
+	for i := 0; i+1 < len(d.root.Content); i += 2 {
+		key := d.root.Content[i]
+		if key.Column != 1 || (len(starts) > 0 && key.Line-1 <= starts[len(starts)-1]) {
+			return
+		}
+
+		keys = append(keys, key)
+		starts = append(starts, key.Line-1)
+	}
+
+	if len(keys) == 0 {
+		return
+	}
+
+	spans := blockSpans(d.lines, starts, 0, 0, len(d.lines))
+	d.prefix = spans[0].start
+
+	for i, key := range keys {
+		value := d.root.Content[2*i+1]
+
+		d.spans[key] = spans[i]
+		d.original[key] = canonical(pairNode(key, value))
+
+		items, column, ok := d.listSpans(value, spans[i])
+		if !ok {
+			continue
+		}
+
+		d.lists[key] = list{node: value, column: column, first: items[0].start}
+		for j, item := range value.Content {
+			d.spans[item] = items[j]
+			d.original[item] = canonical(item)
+		}
+	}
+}
+
+// listSpans returns spans of items of block sequence which is value of key with
+// given span and column of their dashes
+func (d *document) listSpans(value *yamlv3.Node, keySpan span) ([]span, int, bool) {
+	if value.Kind != yamlv3.SequenceNode || value.Style&yamlv3.FlowStyle != 0 || len(value.Content) == 0 {
+		return nil, 0, false
+	}
+
+	column := -1
+	starts := []int{}
+	for _, item := range value.Content {
+		line := item.Line - 1
+		if line <= keySpan.body || line >= keySpan.tail || (len(starts) > 0 && line <= starts[len(starts)-1]) {
+			return nil, 0, false
+		}
+
+		prefix := d.lines[line][:minInt(item.Column-1, len(d.lines[line]))]
+		if !listItemPrefix.MatchString(prefix) {
+			return nil, 0, false
+		}
+
+		dash := strings.IndexByte(prefix, '-')
+		if column >= 0 && dash != column {
+			return nil, 0, false
+		}
+
+		column = dash
+		starts = append(starts, line)
+	}
+
+	return blockSpans(d.lines, starts, column, keySpan.body+1, keySpan.tail), column, true
+}
+
+// blockSpans returns spans of nodes starting at given lines of block [from, to).
+// Blank lines and comments not indented deeper than column belong to the node below them
+func blockSpans(lines []string, starts []int, column, from, to int) []span {
+	detached := func(line string) bool {
+		trimmed := strings.TrimLeft(line, " ")
+		if strings.TrimSpace(trimmed) == "" {
+			return true
+		}
+
+		return strings.HasPrefix(trimmed, "#") && len(line)-len(trimmed) <= column
+	}
+
+	spans := make([]span, len(starts))
+	for i, start := range starts {
+		limit := from
+		if i > 0 {
+			limit = starts[i-1] + 1
+		}
+
+		head := start
+		for head > limit && detached(lines[head-1]) {
+			head--
+		}
+
+		spans[i] = span{start: head, body: start}
+		if i > 0 {
+			spans[i-1].tail, spans[i-1].end = head, head
+		}
+	}
+
+	last := &spans[len(spans)-1]
+	last.tail, last.end = to, to
+	for last.tail > last.body+1 && detached(lines[last.tail-1]) {
+		last.tail--
+	}
+
+	return spans
+}
+
+// encode returns yaml text of document
+func (d *document) encode() ([]byte, error) {
+	if len(d.spans) == 0 {
+		return d.encodeNode(&d.doc)
+	}
+
+	var out bytes.Buffer
+	write := func(text string) {
+		if out.Len() > 0 && !bytes.HasSuffix(out.Bytes(), []byte("\n")) {
+			out.WriteByte('\n')
+		}
+		out.WriteString(text)
+	}
+	copyLines := func(from, to int) {
+		write(strings.Join(d.lines[from:to], ""))
+	}
+
+	copyLines(0, d.prefix)
+
+	for i := 0; i+1 < len(d.root.Content); i += 2 {
+		key, value := d.root.Content[i], d.root.Content[i+1]
+		s, known := d.spans[key]
+
+		switch {
+		case known && canonical(pairNode(key, value)) == d.original[key]:
+			copyLines(s.start, s.end)
+		case known && d.lists[key].node == value && len(value.Content) > 0 && value.Style&yamlv3.FlowStyle == 0:
+			l := d.lists[key]
+			copyLines(s.start, l.first)
+
+			for _, item := range value.Content {
+				text, err := d.encodeItem(item, l.column)
+				if err != nil {
+					return nil, err
+				}
+				write(text)
+			}
+
+			copyLines(s.tail, s.end)
+		default:
+			text, err := d.encodeChanged(pairNode(key, value), key)
+			if err != nil {
+				return nil, err
+			}
+			write(text)
+		}
+	}
+
+	return out.Bytes(), nil
+}
+
+// encodeItem returns text of list item with dash at column, original one if item was not changed
+func (d *document) encodeItem(item *yamlv3.Node, column int) (string, error) {
+	s, known := d.spans[item]
+	if known && canonical(item) == d.original[item] {
+		return strings.Join(d.lines[s.start:s.end], ""), nil
+	}
+
+	text, err := d.encodeChanged(&yamlv3.Node{Kind: yamlv3.SequenceNode, Content: []*yamlv3.Node{item}}, item)
+	if err != nil {
+		return "", err
+	}
+
+	return indentLines(text, column), nil
+}
+
+// encodeChanged encodes node, surrounded by original comments and blank lines
+// of commented node, whose own head and foot comments are dropped for that reason
+func (d *document) encodeChanged(node, commented *yamlv3.Node) (string, error) {
+	s, known := d.spans[commented]
+	if !known {
+		raw, err := d.encodeNode(node)
+		return string(raw), err
+	}
+
+	head, foot := commented.HeadComment, commented.FootComment
+	commented.HeadComment, commented.FootComment = "", ""
+	raw, err := d.encodeNode(node)
+	commented.HeadComment, commented.FootComment = head, foot
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Join(d.lines[s.start:s.body], "") + string(raw) + strings.Join(d.lines[s.tail:s.end], ""), nil
+}
+
+// encodeNode encodes node with indentation of document
+func (d *document) encodeNode(node *yamlv3.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yamlv3.NewEncoder(&buf)
+	encoder.SetIndent(d.indent)
+
+	err := encoder.Encode(node)
+	if err == nil {
+		err = encoder.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if d.compactLists {
+		return outdentLists(buf.Bytes()), nil
+	}
+
+	return buf.Bytes(), nil
+}
+
+// outdentLists moves block lists nested into mappings of yaml text to column of
+// their keys, like kubectl writes them, as yaml.v3 encoder always indents them.
+// Lines are shifted using positions of nodes of reparsed text. Text is returned
+// as is if shifted one does not load the same
+func outdentLists(text []byte) []byte {
+	var doc yamlv3.Node
+	if yamlv3.Unmarshal(text, &doc) != nil {
+		return text
+	}
+
+	lines := strings.SplitAfter(string(text), "\n")
+	shifts := make([]int, len(lines))
+
+	// last returns last line, counting from 1, which belongs to node followed by
+	// next: comments and blank lines not indented deeper than next belong to it
+	last := func(next *yamlv3.Node) int {
+		line := next.Line - 1
+		for line > 0 {
+			trimmed := strings.TrimLeft(lines[line-1], " ")
+			indent := len(lines[line-1]) - len(trimmed)
+			if strings.TrimSpace(trimmed) != "" && (!strings.HasPrefix(trimmed, "#") || indent > next.Column-1) {
+				break
+			}
+			line--
+		}
+		return line
+	}
+
+	var walk func(node *yamlv3.Node, end int)
+	walk = func(node *yamlv3.Node, end int) {
+		switch node.Kind {
+		case yamlv3.DocumentNode:
+			for _, child := range node.Content {
+				walk(child, end)
+			}
+		case yamlv3.SequenceNode:
+			for i, item := range node.Content {
+				itemEnd := end
+				if i+1 < len(node.Content) {
+					itemEnd = last(node.Content[i+1])
+				}
+				walk(item, itemEnd)
+			}
+		case yamlv3.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key, value := node.Content[i], node.Content[i+1]
+
+				valueEnd := end
+				if i+2 < len(node.Content) {
+					valueEnd = last(node.Content[i+2])
+				}
+
+				block := value.Kind == yamlv3.SequenceNode && value.Style&yamlv3.FlowStyle == 0 && len(value.Content) > 0
+				if block && value.Line > key.Line && value.Column > key.Column {
+					for line := value.Line; line <= valueEnd && line <= len(lines); line++ {
+						shifts[line-1] += value.Column - key.Column
+					}
+				}
+
+				walk(value, valueEnd)
+			}
+		}
+	}
+	walk(&doc, len(lines))
+
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " ")
+		shift := minInt(shifts[i], len(line)-len(trimmed))
+		if strings.TrimSpace(trimmed) != "" {
+			lines[i] = line[shift:]
+		}
+	}
+
+	outdented := []byte(strings.Join(lines, ""))
+
+	var check yamlv3.Node
+	if yamlv3.Unmarshal(outdented, &check) != nil || canonical(&check) != canonical(&doc) {
+		return text
+	}
+
+	return outdented
+}
+
+// pairNode returns mapping with single key, used to encode top-level keys separately
+func pairNode(key, value *yamlv3.Node) *yamlv3.Node {
+	return &yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map", Content: []*yamlv3.Node{key, value}}
+}
+
+// canonical returns encoding of node used to tell whether it was changed
+func canonical(node *yamlv3.Node) string {
+	raw, err := yamlv3.Marshal(node)
+	if err != nil {
+		return ""
+	}
+
+	return string(raw)
+}
+
+// indentLines shifts non-empty lines of text right by n spaces
+func indentLines(text string, n int) string {
+	if n <= 0 {
+		return text
+	}
+
+	lines := strings.SplitAfter(text, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			lines[i] = strings.Repeat(" ", n) + line
+		}
+	}
+
+	return strings.Join(lines, "")
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// detectIndent returns indentation of nested mappings of original document and
+// whether lists nested into mappings are written without indentation, like kubectl does
+func detectIndent(root *yamlv3.Node) (int, bool) {
+	indent, indentLists := 0, false
+	listsFound := false
+
+	var walk func(node *yamlv3.Node)
+	walk = func(node *yamlv3.Node) {
+		if node.Kind != yamlv3.MappingNode || node.Style&yamlv3.FlowStyle != 0 {
+			for _, child := range node.Content {
+				walk(child)
+			}
+			return
+		}
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+
+			switch {
+			case value.Style&yamlv3.FlowStyle != 0 || value.Line == key.Line:
+			case value.Kind == yamlv3.MappingNode && indent == 0 && value.Column > key.Column:
+				indent = value.Column - key.Column
+			case value.Kind == yamlv3.SequenceNode && !listsFound && len(value.Content) > 0:
+				listsFound = true
+				indentLists = value.Column > key.Column
+			}
+
+			walk(value)
+		}
+	}
+	walk(root)
+
+	if indent == 0 {
+		indent = 2
+	}
+
+	return indent, !indentLists
+}
+
+// PatchConf applies changes between kubeconfigs before and after to raw kubeconfig
+// before was parsed from. Only changed fields are rewritten: keys konfig does not
+// know, like extensions of users, as well as comments and formatting are kept.
+// Entries of clusters, contexts and users are matched by names
+func PatchConf(raw []byte, before, after Kubeconfig) ([]byte, error) {
+	d, err := parseDocument(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var old, updated yamlv3.Node
+	err = old.Encode(before)
+	if err == nil {
+		err = updated.Encode(after)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	patchMapping(d.root, &old, &updated, func(key string, node, before, after *yamlv3.Node) *yamlv3.Node {
+		if _, named := namedSections[key]; named {
+			return patchList(node, before, after)
+		}
+		return patchNode(node, before, after)
+	})
+
+	return d.encode()
+}
+
+// patchNode returns node, which holds value before, changed to hold value after
+func patchNode(node, before, after *yamlv3.Node) *yamlv3.Node {
+	if node.Kind != yamlv3.MappingNode || before.Kind != yamlv3.MappingNode || after.Kind != yamlv3.MappingNode {
+		if canonical(before) == canonical(after) {
+			return node
+		}
+		return after
+	}
+
+	patchMapping(node, before, after, func(_ string, node, before, after *yamlv3.Node) *yamlv3.Node {
+		return patchNode(node, before, after)
+	})
+
+	return node
+}
+
+// patchMapping changes keys of mapping node which differ between mappings before
+// and after using patch. Keys missing in before are unknown to konfig and kept as is
+func patchMapping(node, before, after *yamlv3.Node, patch func(key string, node, before, after *yamlv3.Node) *yamlv3.Node) {
+	for i := 0; i+1 < len(before.Content); i += 2 {
+		key := before.Content[i].Value
+		if lookupMapping(after, key) == nil {
+			removeMappingKey(node, key)
+		}
+	}
+
+	for i := 0; i+1 < len(after.Content); i += 2 {
+		key, value := after.Content[i].Value, after.Content[i+1]
+		current, old := lookupMapping(node, key), lookupMapping(before, key)
+
+		switch {
+		case current == nil:
+			if old == nil || canonical(old) != canonical(value) {
+				setMappingValue(node, key, value)
+			}
+		case old == nil:
+			setMappingValue(node, key, value)
+		default:
+			if patched := patch(key, current, old, value); patched != current {
+				setMappingValue(node, key, patched)
+			}
+		}
+	}
+}
+
+// patchList returns list node of named entries, which holds entries before,
+// changed to hold entries after. Entries with the same name are patched in place,
+// other ones are removed or added, and order of after is kept
+func patchList(node, before, after *yamlv3.Node) *yamlv3.Node {
+	if canonical(before) == canonical(after) {
+		return node
+	}
+
+	if node.Kind != yamlv3.SequenceNode || before.Kind != yamlv3.SequenceNode ||
+		after.Kind != yamlv3.SequenceNode || len(node.Content) != len(before.Content) {
+		return after
+	}
+
+	// entries with the same name are matched in order they appear
+	indices := map[string][]int{}
+	for i, entry := range before.Content {
+		name := entryNodeName(entry)
+		indices[name] = append(indices[name], i)
+	}
+
+	content := []*yamlv3.Node{}
+	for _, entry := range after.Content {
+		name := entryNodeName(entry)
+		if len(indices[name]) == 0 {
+			content = append(content, entry)
+			continue
+		}
+
+		i := indices[name][0]
+		indices[name] = indices[name][1:]
+		content = append(content, patchNode(node.Content[i], before.Content[i], entry))
+	}
+
+	if len(content) == 0 {
+		node.Style |= yamlv3.FlowStyle
+	} else if len(node.Content) == 0 {
+		node.Style &^= yamlv3.FlowStyle
+	}
+	node.Content = content
+
+	return node
+}
+
+// entryNodeName returns name of entry of named list
+func entryNodeName(entry *yamlv3.Node) string {
+	if name := lookupMapping(entry, "name"); name != nil {
+		return name.Value
+	}
+	return ""
+}
@@ -0,0 +1,217 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+const commentedConf = `# managed by hand
+
+apiVersion: v1
+kind: Config
+clusters:
+# staging goes first
+- name: staging
+  cluster:
+    server: 'https://staging' # behind vpn
+    proxy-url: http://proxy:3128
+
+# production
+- name: prod
+  cluster:
+    server: https://prod
+    note: |
+      key:
+      - not a list
+contexts:
+  - name: prod
+    context:
+      cluster: prod
+      user: admin
+
+  - name: staging
+    context:
+      cluster: staging
+      user: admin
+current-context: prod
+users: []
+# the end
+`
+
+func TestDocumentKeepsUnchangedText(t *testing.T) {
+	for _, raw := range []string{commentedConf, editableConf, "kind: Config", "{kind: Config}\n", "kind: Config\nusers: [{name: a}]\n"} {
+		d, err := parseDocument([]byte(raw))
+		require.NoError(t, err)
+
+		result, err := d.encode()
+		require.NoError(t, err)
+		require.Equal(t, raw, string(result))
+	}
+}
+
+func TestDocumentEncodesChangedEntries(t *testing.T) {
+	d, err := parseDocument([]byte(commentedConf))
+	require.NoError(t, err)
+
+	clusters := lookupMapping(d.root, "clusters")
+	clusters.Content = clusters.Content[1:]
+	setMappingValue(lookupMapping(clusters.Content[0], "cluster"), "server", stringNode("https://prod:6443"))
+
+	contexts := lookupMapping(d.root, "contexts")
+	context := newMapping()
+	setMappingValue(context, "name", stringNode("dev"))
+	setMappingValue(context, "context", newMapping())
+	setMappingValue(lookupMapping(context, "context"), "cluster", stringNode("dev"))
+	appendToCollection(contexts, context)
+
+	user := newMapping()
+	setMappingValue(user, "name", stringNode("admin"))
+	appendToCollection(lookupMapping(d.root, "users"), user)
+
+	result, err := d.encode()
+	require.NoError(t, err)
+	require.Equal(t, `# managed by hand
+
+apiVersion: v1
+kind: Config
+clusters:
+
+# production
+- name: prod
+  cluster:
+    server: https://prod:6443
+    note: |
+      key:
+      - not a list
+contexts:
+  - name: prod
+    context:
+      cluster: prod
+      user: admin
+
+  - name: staging
+    context:
+      cluster: staging
+      user: admin
+  - name: dev
+    context:
+      cluster: dev
+current-context: prod
+users:
+- name: admin
+# the end
+`, string(result))
+}
+
+func TestDocumentEncodesCompactLists(t *testing.T) {
+	d, err := parseDocument([]byte("kind: Config\nusers:\n- name: old\n"))
+	require.NoError(t, err)
+
+	var user yamlv3.Node
+	require.NoError(t, yamlv3.Unmarshal([]byte(`name: exec
+user:
+  exec:
+    # plugin arguments
+    args:
+    - eks
+    - get-token
+    env:
+    - name: AWS_PROFILE
+      value: prod
+    note: |
+      - not a list
+        indented
+  # trailing
+  token: abc
+`), &user))
+	lookupMapping(d.root, "users").Content[0] = user.Content[0]
+
+	result, err := d.encode()
+	require.NoError(t, err)
+	require.Equal(t, `kind: Config
+users:
+- name: exec
+  user:
+    exec:
+      # plugin arguments
+      args:
+      - eks
+      - get-token
+      env:
+      - name: AWS_PROFILE
+        value: prod
+      note: |
+        - not a list
+          indented
+    # trailing
+    token: abc
+`, string(result))
+}
+
+func TestParseDocumentSeedsEmptyKubeconfig(t *testing.T) {
+	d, err := parseDocument(nil)
+	require.NoError(t, err)
+
+	result, err := d.encode()
+	require.NoError(t, err)
+	require.Equal(t, "apiVersion: v1\nkind: Config\n", string(result))
+
+	_, err = parseDocument([]byte("- a\n"))
+	require.Equal(t, ExitValidation, ExitCode(err))
+}
+
+func TestPatchConf(t *testing.T) {
+	raw := []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: prod # primary
+  cluster:
+    server: "https://prod"
+    proxy-url: http://proxy:3128
+- name: old
+  cluster:
+    server: https://old
+users:
+- name: oidc
+  user:
+    auth-provider:
+      name: oidc
+    as: admin
+`)
+	before, err := ParseConf(raw)
+	require.NoError(t, err)
+
+	after, err := ParseConf(raw)
+	require.NoError(t, err)
+	after.Clusters[0].Cluster.InsecureSkipTLSVerify = true
+	after.Clusters = append(after.Clusters[:1], ClusterEntry{Name: "new", Cluster: Cluster{Server: "https://new"}})
+	after.Users[0].User.As = ""
+	after.CurrentContext = "prod"
+
+	result, err := PatchConf(raw, before, after)
+	require.NoError(t, err)
+	require.Equal(t, `apiVersion: v1
+kind: Config
+clusters:
+- name: prod # primary
+  cluster:
+    server: "https://prod"
+    proxy-url: http://proxy:3128
+    insecure-skip-tls-verify: true
+- cluster:
+    server: https://new
+  name: new
+users:
+- name: oidc
+  user:
+    auth-provider:
+      name: oidc
+current-context: prod
+`, string(result))
+
+	result, err = PatchConf(raw, before, before)
+	require.NoError(t, err)
+	require.Equal(t, string(raw), string(result))
+}
@@ -0,0 +1,489 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// editAnnotation starts lines konfig puts on top of edited file to describe errors
+const editAnnotation = "# konfig: "
+
+// errEditUnchanged is returned when edited file is saved without fixing reported errors
+var errEditUnchanged = errors.New("edit cancelled, errors were not fixed")
+
+// Editor lets user edit file at path
+type Editor func(path string) error
+
+// SystemEditor opens file in $KUBE_EDITOR or $EDITOR, vi if neither is set
+func SystemEditor(path string) error {
+	editor := os.Getenv("KUBE_EDITOR")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// editors are often set with flags, e.g. "code --wait"
+	args := strings.Fields(editor)
+
+	cmd := exec.Command(args[0], append(args[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf("editor %s failed: %w", editor, err)
+	}
+
+	return nil
+}
+
+// EditConf lets user edit kubeconfig at path, or only context with its cluster
+// and user if context is set. Edited kubeconfig is parsed and validated, on
+// failure editor is reopened with errors on top. Kubeconfig is written atomically
+// only after successful validation, it returns false if nothing was changed
+func EditConf(path, context string, timeout time.Duration, editor Editor) (bool, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return false, fmt.Errorf("cannot open kubeconfig: %w", err)
+	}
+
+	config, err := ReadConf(path)
+	if err != nil {
+		return false, err
+	}
+
+	dir := filepath.Dir(path)
+	known := Validate(config, dir)
+
+	content := raw
+	var subset Kubeconfig
+
+	if context != "" {
+		subset, err = Minify(config, context)
+		if err != nil {
+			return false, err
+		}
+
+		content, err = subsetDocument(raw, subset)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	// apply turns edited content into new content of kubeconfig
+	apply := func(edited []byte) ([]byte, error) {
+		result, err := ParseConf(edited)
+		if err != nil {
+			return nil, err
+		}
+
+		if context != "" {
+			result, err = ReplaceEntries(config, subset, result)
+			if err != nil {
+				return nil, err
+			}
+
+			edited, err = spliceEntries(raw, edited, subset, result)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if findings := NewErrors(known, Validate(result, dir)); len(findings) > 0 {
+			lines := []string{}
+			for _, finding := range findings {
+				lines = append(lines, fmt.Sprintf("%s: %s", finding.Location(), finding.Message))
+			}
+
+			return nil, WithExitCode(ExitValidation, errors.New(strings.Join(lines, "\n")))
+		}
+
+		return edited, nil
+	}
+
+	var result []byte
+	edited, err := EditLoop(content, editor, func(edited []byte) error {
+		result, err = apply(edited)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if bytes.Equal(edited, content) {
+		return false, nil
+	}
+
+	err = UpdateRawConf(path, timeout, func(current []byte) ([]byte, error) {
+		if bytes.Equal(current, raw) {
+			return result, nil
+		}
+
+		file, err := os.CreateTemp("", "konfig-edit-*.yaml")
+		if err == nil {
+			_, err = file.Write(edited)
+			file.Close()
+		}
+		if err != nil {
+			return nil, WithExitCode(ExitConflict, errors.New("kubeconfig was changed while editing, changes are lost"))
+		}
+
+		return nil, WithExitCode(ExitConflict, fmt.Errorf(
+			"kubeconfig was changed while editing, changes are saved to %s", file.Name(),
+		))
+	})
+
+	return err == nil, err
+}
+
+// EditLoop opens content in editor until check accepts edited content. Rejected
+// content is reopened with error on top. Saving empty file cancels editing
+func EditLoop(content []byte, editor Editor, check func([]byte) error) ([]byte, error) {
+	file, err := os.CreateTemp("", "konfig-edit-*.yaml")
+	if err != nil {
+		return nil, err
+	}
+	file.Close()
+	defer os.Remove(file.Name())
+
+	var rejected []byte
+
+	for {
+		err = os.WriteFile(file.Name(), content, os.FileMode(0600))
+		if err != nil {
+			return nil, err
+		}
+
+		err = editor(file.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		edited, err := os.ReadFile(file.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		edited = stripAnnotations(edited)
+		if len(bytes.TrimSpace(edited)) == 0 {
+			return nil, ErrAborted
+		}
+
+		if rejected != nil && bytes.Equal(edited, rejected) {
+			return nil, WithExitCode(ExitValidation, errEditUnchanged)
+		}
+
+		err = check(edited)
+		if err == nil {
+			return edited, nil
+		}
+
+		rejected = edited
+		content = annotate(edited, err)
+	}
+}
+
+// annotate puts error as comments on top of content
+func annotate(content []byte, err error) []byte {
+	var buf bytes.Buffer
+
+	buf.WriteString(editAnnotation + "kubeconfig is not valid, fix errors below or save empty file to cancel\n")
+	for _, line := range strings.Split(err.Error(), "\n") {
+		buf.WriteString(editAnnotation + line + "\n")
+	}
+	buf.Write(content)
+
+	return buf.Bytes()
+}
+
+// stripAnnotations removes comments added by annotate
+func stripAnnotations(content []byte) []byte {
+	for bytes.HasPrefix(content, []byte(editAnnotation)) {
+		end := bytes.IndexByte(content, '\n')
+		if end < 0 {
+			return nil
+		}
+
+		content = content[end+1:]
+	}
+
+	return content
+}
+
+// subsetDocument returns part of raw kubeconfig with clusters, contexts and users
+// of subset only, keeping all their fields
+func subsetDocument(raw []byte, subset Kubeconfig) ([]byte, error) {
+	d, err := parseDocument(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	root := newMapping()
+	for _, key := range []string{"apiVersion", "kind"} {
+		if value := lookupMapping(d.root, key); value != nil {
+			setMappingValue(root, key, value)
+		}
+	}
+
+	names := subsetNames(subset)
+	for _, section := range []string{"clusters", "contexts", "users"} {
+		list := &yamlv3.Node{Kind: yamlv3.SequenceNode, Tag: "!!seq"}
+		for _, name := range names[section] {
+			list.Content = append(list.Content, findEntry(lookupMapping(d.root, section), name))
+		}
+		setMappingValue(root, section, list)
+
+		if section == "contexts" {
+			setMappingValue(root, "current-context", stringNode(subset.CurrentContext))
+		}
+	}
+
+	return d.encodeNode(&yamlv3.Node{Kind: yamlv3.DocumentNode, Content: []*yamlv3.Node{root}})
+}
+
+// spliceEntries returns raw kubeconfig with clusters, contexts and users of subset
+// replaced by entries of edited raw content, so it becomes expected kubeconfig.
+// Other entries and entries which were not changed are kept as is
+func spliceEntries(raw, edited []byte, subset, expected Kubeconfig) ([]byte, error) {
+	d, err := parseDocument(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc yamlv3.Node
+	err = yamlv3.Unmarshal(edited, &doc)
+	if err != nil {
+		return nil, WithExitCode(ExitValidation, fmt.Errorf("cannot parse kubeconfig: %w", err))
+	}
+
+	var editedRoot *yamlv3.Node
+	if len(doc.Content) > 0 {
+		editedRoot = doc.Content[0]
+	}
+
+	entries := func(list *yamlv3.Node) ([]*yamlv3.Node, []string) {
+		names := []string{}
+		if list == nil || list.Kind != yamlv3.SequenceNode {
+			return nil, names
+		}
+
+		for _, entry := range list.Content {
+			names = append(names, entryNodeName(entry))
+		}
+		return list.Content, names
+	}
+
+	names := subsetNames(subset)
+	for _, section := range []string{"clusters", "contexts", "users"} {
+		current, currentNames := entries(lookupMapping(d.root, section))
+		changed, changedNames := entries(lookupMapping(editedRoot, section))
+
+		order, err := mergeOrder(namedSections[section].key, currentNames, names[section], changedNames)
+		if err != nil {
+			return nil, err
+		}
+
+		content := []*yamlv3.Node{}
+		for _, i := range order {
+			if i >= 0 {
+				content = append(content, current[i])
+				continue
+			}
+
+			entry := changed[-1-i]
+			// entries which were not changed keep their original text
+			if original := findEntry(lookupMapping(d.root, section), entryNodeName(entry)); original != nil && sameContent(original, entry) {
+				entry = original
+			}
+			content = append(content, entry)
+		}
+
+		if len(content) == 0 && current == nil {
+			continue
+		}
+
+		list := mappingValue(d.root, section, yamlv3.SequenceNode)
+		list.Content = nil
+		appendToCollection(list, content...)
+	}
+
+	if expected.CurrentContext != lookupString(d.root, "current-context") {
+		setMappingValue(d.root, "current-context", stringNode(expected.CurrentContext))
+	}
+
+	result, err := d.encode()
+	if err != nil {
+		return nil, err
+	}
+
+	spliced, err := ParseConf(result)
+	if err != nil {
+		return nil, err
+	}
+
+	spliced.Dir = expected.Dir
+	if !reflect.DeepEqual(spliced, expected) {
+		return nil, errors.New("edited kubeconfig differs from expected one")
+	}
+
+	return result, nil
+}
+
+// subsetNames returns names of entries of subset by top-level keys of their lists
+func subsetNames(subset Kubeconfig) map[string][]string {
+	return map[string][]string{
+		"clusters": ClusterNames(subset),
+		"contexts": ContextNames(subset),
+		"users":    UserNames(subset),
+	}
+}
+
+// sameContent reports whether nodes hold equal values, regardless of formatting
+func sameContent(a, b *yamlv3.Node) bool {
+	var valueA, valueB interface{}
+	if a.Decode(&valueA) != nil || b.Decode(&valueB) != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(valueA, valueB)
+}
+
+// lookupString returns value of scalar key of mapping node, empty if it is missing
+func lookupString(mapping *yamlv3.Node, key string) string {
+	if value := lookupMapping(mapping, key); value != nil && value.Kind == yamlv3.ScalarNode {
+		return value.Value
+	}
+	return ""
+}
+
+// NewErrors returns error findings of after which are not among before
+func NewErrors(before, after []Finding) []Finding {
+	known := map[Finding]bool{}
+	for _, finding := range before {
+		known[finding] = true
+	}
+
+	errs := []Finding{}
+	for _, finding := range after {
+		if finding.Severity == SeverityError && !known[finding] {
+			errs = append(errs, finding)
+		}
+	}
+
+	return errs
+}
+
+// ReplaceEntries returns copy of kubeconfig with clusters, contexts and users of
+// subset replaced by ones of edited. Replaced entries keep their positions, new
+// ones are appended. If current context is renamed, current-context follows it
+func ReplaceEntries(k, subset, edited Kubeconfig) (Kubeconfig, error) {
+	result := k
+
+	order, err := mergeOrder("cluster", ClusterNames(k), ClusterNames(subset), ClusterNames(edited))
+	if err != nil {
+		return Kubeconfig{}, err
+	}
+
+	result.Clusters = []ClusterEntry{}
+	for _, i := range order {
+		if i >= 0 {
+			result.Clusters = append(result.Clusters, k.Clusters[i])
+		} else {
+			result.Clusters = append(result.Clusters, edited.Clusters[-1-i])
+		}
+	}
+
+	order, err = mergeOrder("context", ContextNames(k), ContextNames(subset), ContextNames(edited))
+	if err != nil {
+		return Kubeconfig{}, err
+	}
+
+	result.Contexts = []ContextEntry{}
+	for _, i := range order {
+		if i >= 0 {
+			result.Contexts = append(result.Contexts, k.Contexts[i])
+		} else {
+			result.Contexts = append(result.Contexts, edited.Contexts[-1-i])
+		}
+	}
+
+	order, err = mergeOrder("user", UserNames(k), UserNames(subset), UserNames(edited))
+	if err != nil {
+		return Kubeconfig{}, err
+	}
+
+	result.Users = []UserEntry{}
+	for _, i := range order {
+		if i >= 0 {
+			result.Users = append(result.Users, k.Users[i])
+		} else {
+			result.Users = append(result.Users, edited.Users[-1-i])
+		}
+	}
+
+	if _, ok := FindContext(result, k.CurrentContext); !ok && k.CurrentContext != "" {
+		result.CurrentContext = ""
+		if _, ok := FindContext(edited, edited.CurrentContext); ok {
+			result.CurrentContext = edited.CurrentContext
+		} else if len(edited.Contexts) == 1 {
+			result.CurrentContext = edited.Contexts[0].Name
+		}
+	}
+
+	return result, nil
+}
+
+// mergeOrder returns order of entries named current after entries named old
+// are replaced by edited ones. Non-negative values are indexes of current entries,
+// negative ones are indexes of edited entries, counted as -1-index
+func mergeOrder(section string, current, old, edited []string) ([]int, error) {
+	replaced := map[string]bool{}
+	for _, name := range old {
+		replaced[name] = true
+	}
+
+	editedIndex := map[string]int{}
+	for i, name := range edited {
+		if _, ok := editedIndex[name]; ok {
+			return nil, WithExitCode(ExitValidation, fmt.Errorf("duplicate %s name %q", section, name))
+		}
+		editedIndex[name] = i
+	}
+
+	order := []int{}
+	placed := map[string]bool{}
+
+	for i, name := range current {
+		j, ok := editedIndex[name]
+
+		switch {
+		case ok && !replaced[name]:
+			return nil, WithExitCode(ExitConflict, fmt.Errorf("%s %q already exists", section, name))
+		case ok:
+			order = append(order, -1-j)
+			placed[name] = true
+		case !replaced[name]:
+			order = append(order, i)
+		}
+	}
+
+	for j, name := range edited {
+		if !placed[name] {
+			order = append(order, -1-j)
+		}
+	}
+
+	return order, nil
+}
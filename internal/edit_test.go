@@ -0,0 +1,125 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// replacingEditor returns editor which replaces old with new in edited file
+// and records content it was opened with
+func replacingEditor(opened *[]string, replacements ...string) Editor {
+	return func(path string) error {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		*opened = append(*opened, string(raw))
+
+		content := string(raw)
+		if len(replacements) >= 2 {
+			content = strings.Replace(content, replacements[0], replacements[1], 1)
+			replacements = replacements[2:]
+		}
+
+		return os.WriteFile(path, []byte(content), 0600)
+	}
+}
+
+func TestEditConf(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(path, []byte(editableConf), 0600))
+
+	opened := []string{}
+	changed, err := EditConf(path, "", time.Second, replacingEditor(&opened,
+		"server: \"https://prod:6443\"", "server: prod:6443",
+		"server: prod:6443", "server: https://prod.example.com",
+	))
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.Len(t, opened, 2)
+	require.True(t, strings.HasPrefix(opened[1], editAnnotation+"kubeconfig is not valid"))
+	require.Contains(t, opened[1], "cluster prod server")
+
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, strings.Replace(editableConf, "\"https://prod:6443\"", "https://prod.example.com", 1), string(raw))
+
+	opened = nil
+	changed, err = EditConf(path, "", time.Second, replacingEditor(&opened))
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	opened = nil
+	_, err = EditConf(path, "", time.Second, replacingEditor(&opened, "server: https://gke", "server: gke"))
+	require.Equal(t, ExitValidation, ExitCode(err))
+	require.Len(t, opened, 2)
+
+	_, err = EditConf(path, "", time.Second, func(path string) error {
+		return os.WriteFile(path, nil, 0600)
+	})
+	require.ErrorIs(t, err, ErrAborted)
+
+	raw, err = os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(raw), "server: https://gke\n")
+}
+
+func TestEditConfContext(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(path, []byte(editableConf), 0600))
+
+	opened := []string{}
+	changed, err := EditConf(path, "prod", time.Second, replacingEditor(&opened,
+		"- name: prod\n  context:", "- name: production\n  context:\n    namespace: payments",
+	))
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.NotContains(t, opened[0], "gke")
+
+	config, err := ReadConf(path)
+	require.NoError(t, err)
+	require.Equal(t, []ContextEntry{
+		{Name: "production", Context: Context{Cluster: "prod", User: "admin", Namespace: "payments"}},
+	}, config.Contexts)
+	require.Equal(t, "production", config.CurrentContext)
+	require.Equal(t, []string{"prod", "gke.example.com"}, ClusterNames(config))
+
+	_, err = EditConf(path, "production", time.Second, replacingEditor(&opened,
+		"- name: prod # primary\n", "- name: gke.example.com\n",
+		"", "",
+	))
+	require.Equal(t, ExitValidation, ExitCode(err))
+	require.Contains(t, opened[len(opened)-1], "cluster \"gke.example.com\" already exists")
+}
+
+func TestEditConfContextKeepsUnknownFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(path, []byte(unknownFieldsConf), 0600))
+
+	opened := []string{}
+	changed, err := EditConf(path, "prod", time.Second, replacingEditor(&opened, "client-id: konfig", "client-id: kubectl"))
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.Contains(t, opened[0], "    proxy-url: http://proxy:3128 # office proxy\n")
+	require.Contains(t, opened[0], "        color: red\n")
+
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, strings.Replace(unknownFieldsConf, "client-id: konfig", "client-id: kubectl", 1), string(raw))
+
+	changed, err = EditConf(path, "dev", time.Second, replacingEditor(&opened, "user: dev\n", "user: dev\n    namespace: test\n"))
+	require.NoError(t, err)
+	require.True(t, changed)
+
+	raw, err = os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, strings.NewReplacer(
+		"client-id: konfig", "client-id: kubectl",
+		"    user: dev\n", "    user: dev\n    namespace: test\n",
+	).Replace(unknownFieldsConf), string(raw))
+}
@@ -0,0 +1,81 @@
+package internal
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// Exit codes konfig terminates with. Scripts may rely on them, so never renumber
+const (
+	// ExitOK means command succeeded
+	ExitOK = 0
+	// ExitFailure means command failed for reason not covered by other codes
+	ExitFailure = 1
+	// ExitUsage means command was called with wrong arguments or flags
+	ExitUsage = 2
+	// ExitNotFound means kubeconfig, backup or another requested object does not exist
+	ExitNotFound = 3
+	// ExitConflict means command cannot proceed because of conflicting state,
+	// e.g. kubeconfig is locked by another program or entries cannot be merged
+	ExitConflict = 4
+	// ExitValidation means kubeconfig or other input is malformed
+	ExitValidation = 5
+	// ExitIO means reading or writing files failed
+	ExitIO = 6
+	// ExitFindings means command succeeded, but reported differences or problems
+	ExitFindings = 7
+)
+
+// ErrFindings is returned by commands which reported differences or problems.
+// It is not printed, since the report itself is the output of such commands
+var ErrFindings = errors.New("findings reported")
+
+// Error is error which carries exit code for konfig to terminate with
+type Error struct {
+	Code int
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// WithExitCode annotates err with exit code
+func WithExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &Error{Code: code, Err: err}
+}
+
+// ExitCode returns exit code konfig should terminate with because of err
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+
+	var pathErr *fs.PathError
+
+	switch {
+	case errors.Is(err, ErrFindings):
+		return ExitFindings
+	case errors.Is(err, fs.ErrNotExist):
+		return ExitNotFound
+	case errors.Is(err, ErrLockTimeout), errors.Is(err, ErrStaleLock):
+		return ExitConflict
+	case errors.As(err, &pathErr):
+		return ExitIO
+	default:
+		return ExitFailure
+	}
+}
@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExitCode(t *testing.T) {
+	_, notFound := os.ReadFile(filepath.Join(t.TempDir(), "missing"))
+	_, isDir := os.ReadFile(t.TempDir())
+
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{name: "no error", err: nil, expected: ExitOK},
+		{name: "plain error", err: errors.New("boom"), expected: ExitFailure},
+		{name: "explicit code", err: WithExitCode(ExitUsage, errors.New("bad flag")), expected: ExitUsage},
+		{name: "wrapped explicit code", err: fmt.Errorf("merge: %w", WithExitCode(ExitConflict, errors.New("kind"))), expected: ExitConflict},
+		{name: "missing file", err: fmt.Errorf("cannot open kubeconfig: %w", notFound), expected: ExitNotFound},
+		{name: "lock timeout", err: fmt.Errorf("%w: config.lock", ErrLockTimeout), expected: ExitConflict},
+		{name: "io failure", err: isDir, expected: ExitIO},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, ExitCode(tc.err))
+		})
+	}
+}
+
+func TestReadConfExitCodes(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := ReadConf(filepath.Join(dir, "missing"))
+	require.Equal(t, ExitNotFound, ExitCode(err))
+
+	broken := filepath.Join(dir, "broken")
+	require.NoError(t, os.WriteFile(broken, []byte("kind: ["), 0600))
+
+	_, err = ReadConf(broken)
+	require.Equal(t, ExitValidation, ExitCode(err))
+}
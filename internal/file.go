@@ -0,0 +1,215 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+// maxSymlinks limits how many symlinks are followed while resolving write target
+const maxSymlinks = 255
+
+// WriteFileAtomic replaces content of file at path so that readers never see partially
+// written data: content goes to a temp file in the same folder, which is synced and
+// renamed over the target. Mode and owner of existing file are preserved, perm is used
+// for new files only. If path is a symlink, the file it points to is updated instead
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) (err error) {
+	target, err := ResolveSymlinks(path)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(target)
+	switch {
+	case err == nil:
+		perm = info.Mode().Perm()
+	case errors.Is(err, os.ErrNotExist):
+		info = nil
+	default:
+		return err
+	}
+
+	dir := filepath.Dir(target)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(target)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("cannot write %s: %w", path, err)
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		return fmt.Errorf("cannot write %s: %w", path, err)
+	}
+
+	if err = tmp.Sync(); err != nil {
+		return fmt.Errorf("cannot write %s: %w", path, err)
+	}
+
+	if err = tmp.Chmod(perm); err != nil {
+		return err
+	}
+
+	if info != nil {
+		if err = preserveOwner(tmp, info); err != nil {
+			return err
+		}
+	}
+
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("cannot write %s: %w", path, err)
+	}
+
+	if err = os.Rename(tmp.Name(), target); err != nil {
+		return fmt.Errorf("cannot write %s: %w", path, err)
+	}
+
+	return syncDir(dir)
+}
+
+// ResolveSymlinks returns path of the file which is finally pointed to by path.
+// Unlike filepath.EvalSymlinks, it works for dangling links and missing files
+func ResolveSymlinks(path string) (string, error) {
+	for i := 0; i < maxSymlinks; i++ {
+		info, err := os.Lstat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return path, nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		if info.Mode()&os.ModeSymlink == 0 {
+			return path, nil
+		}
+
+		link, err := os.Readlink(path)
+		if err != nil {
+			return "", err
+		}
+
+		path = ResolvePath(filepath.Dir(path), link)
+	}
+
+	return "", fmt.Errorf("too many levels of symbolic links: %s", path)
+}
+
+// FileChanges records files written by operation, so they can be rolled back
+// if it fails later: created files are removed, replaced ones get previous content back
+type FileChanges struct {
+	changes []fileChange
+}
+
+type fileChange struct {
+	path     string
+	existed  bool
+	previous []byte
+	mode     os.FileMode
+}
+
+// Write atomically stores data at path like WriteFileAtomic, remembering previous content
+func (c *FileChanges) Write(path string, data []byte, perm os.FileMode) error {
+	change := fileChange{path: path, existed: true}
+
+	info, err := os.Stat(path)
+	switch {
+	case err == nil:
+		change.mode = info.Mode().Perm()
+		change.previous, err = os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+	case errors.Is(err, os.ErrNotExist):
+		change.existed = false
+	default:
+		return err
+	}
+
+	err = WriteFileAtomic(path, data, perm)
+	if err != nil {
+		return err
+	}
+
+	c.changes = append(c.changes, change)
+	return nil
+}
+
+// Paths returns written files in order they were written
+func (c *FileChanges) Paths() []string {
+	paths := []string{}
+	for _, change := range c.changes {
+		paths = append(paths, change.path)
+	}
+
+	return paths
+}
+
+// Rollback undoes writes in reverse order, ignoring errors
+func (c *FileChanges) Rollback() {
+	for i := len(c.changes) - 1; i >= 0; i-- {
+		change := c.changes[i]
+		if change.existed {
+			_ = WriteFileAtomic(change.path, change.previous, change.mode)
+			_ = os.Chmod(change.path, change.mode)
+		} else {
+			_ = os.Remove(change.path)
+		}
+	}
+
+	c.changes = nil
+}
+
+// WriteConf atomically stores kubeconfig at path. If kubeconfig was read from
+// another folder, relative file references are rewritten to stay valid
+func WriteConf(path string, k Kubeconfig) error {
+	raw, err := marshalConf(path, k)
+	if err != nil {
+		return err
+	}
+
+	return WriteFileAtomic(path, raw, os.FileMode(0600))
+}
+
+// marshalConf encodes kubeconfig to be stored at path, rebasing relative file references
+func marshalConf(path string, k Kubeconfig) ([]byte, error) {
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+
+	if k.Dir != "" && k.Dir != dir {
+		var warnings []string
+		k, warnings = RebasePaths(k, dir)
+		Warn(warnings...)
+	}
+
+	return yaml.Marshal(k)
+}
+
+// WritePatchedConf is WriteConf for kubeconfig after, which is changed kubeconfig
+// before parsed from raw content. Only changed fields of raw content are rewritten
+func WritePatchedConf(path string, raw []byte, before, after Kubeconfig) error {
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+
+	if after.Dir != "" && after.Dir != dir {
+		var warnings []string
+		after, warnings = RebasePaths(after, dir)
+		Warn(warnings...)
+	}
+
+	raw, err = PatchConf(raw, before, after)
+	if err != nil {
+		return err
+	}
+
+	return WriteFileAtomic(path, raw, os.FileMode(0600))
+}
@@ -0,0 +1,89 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("new file", func(t *testing.T) {
+		path := filepath.Join(dir, "new")
+		require.NoError(t, WriteFileAtomic(path, []byte("data"), 0600))
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, "data", string(data))
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	})
+
+	t.Run("existing file keeps mode", func(t *testing.T) {
+		path := filepath.Join(dir, "existing")
+		require.NoError(t, os.WriteFile(path, []byte("old"), 0640))
+		require.NoError(t, os.Chmod(path, 0640))
+		require.NoError(t, WriteFileAtomic(path, []byte("new"), 0600))
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0640), info.Mode().Perm())
+	})
+
+	t.Run("symlink target is updated", func(t *testing.T) {
+		require.NoError(t, os.Mkdir(filepath.Join(dir, "dotfiles"), 0700))
+		target := filepath.Join(dir, "dotfiles", "config")
+		link := filepath.Join(dir, "link")
+		require.NoError(t, os.WriteFile(target, []byte("old"), 0600))
+		require.NoError(t, os.Symlink(filepath.Join("dotfiles", "config"), link))
+		require.NoError(t, WriteFileAtomic(link, []byte("new"), 0600))
+
+		info, err := os.Lstat(link)
+		require.NoError(t, err)
+		require.NotZero(t, info.Mode()&os.ModeSymlink)
+
+		data, err := os.ReadFile(target)
+		require.NoError(t, err)
+		require.Equal(t, "new", string(data))
+	})
+
+	t.Run("no temp files left", func(t *testing.T) {
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		for _, entry := range entries {
+			require.NotContains(t, entry.Name(), ".tmp-")
+		}
+	})
+}
+
+func TestFileChangesRollback(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "admin.key")
+	created := filepath.Join(dir, "admin.crt")
+	require.NoError(t, os.WriteFile(existing, []byte("old key"), 0600))
+
+	files := &FileChanges{}
+	require.NoError(t, files.Write(existing, []byte("new key"), 0600))
+	require.NoError(t, files.Write(created, []byte("new cert"), 0644))
+	require.NoError(t, files.Write(existing, []byte("newer key"), 0600))
+	require.Equal(t, []string{existing, created, existing}, files.Paths())
+
+	content, err := os.ReadFile(existing)
+	require.NoError(t, err)
+	require.Equal(t, "newer key", string(content))
+
+	files.Rollback()
+
+	content, err = os.ReadFile(existing)
+	require.NoError(t, err)
+	require.Equal(t, "old key", string(content))
+
+	_, err = os.Stat(created)
+	require.ErrorIs(t, err, os.ErrNotExist)
+	require.Empty(t, files.Paths())
+}
@@ -0,0 +1,42 @@
+//go:build !windows
+// +build !windows
+
+package internal
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// preserveOwner gives file f the same owner as described by info.
+// Failures caused by lack of privileges are ignored
+func preserveOwner(f *os.File, info os.FileInfo) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+
+	err := f.Chown(int(stat.Uid), int(stat.Gid))
+	if err != nil && !errors.Is(err, os.ErrPermission) {
+		return err
+	}
+
+	return nil
+}
+
+// syncDir flushes folder entries so that rename is persisted on disk
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+
+	err = d.Sync()
+	closeErr := d.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	return err
+}
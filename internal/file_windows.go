@@ -0,0 +1,16 @@
+//go:build windows
+// +build windows
+
+package internal
+
+import "os"
+
+// preserveOwner does nothing on windows, where files inherit folder ACLs
+func preserveOwner(f *os.File, info os.FileInfo) error {
+	return nil
+}
+
+// syncDir does nothing on windows, where folders cannot be synced
+func syncDir(dir string) error {
+	return nil
+}
@@ -0,0 +1,157 @@
+package internal
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Flatten returns self-contained copy of kubeconfig: content of every referenced
+// certificate, key and token file is inlined into corresponding data field and
+// path field is dropped. Relative paths are resolved against dir
+func Flatten(k Kubeconfig, dir string) (Kubeconfig, error) {
+	k.Clusters = append([]ClusterEntry{}, k.Clusters...)
+	k.Users = append([]UserEntry{}, k.Users...)
+
+	for i := range k.Clusters {
+		cluster := &k.Clusters[i].Cluster
+
+		err := inlineFile(dir, &cluster.CertificateAuthority, &cluster.CertificateAuthorityData)
+		if err != nil {
+			return Kubeconfig{}, fmt.Errorf("cluster %s: %w", k.Clusters[i].Name, err)
+		}
+	}
+
+	for i := range k.Users {
+		user := &k.Users[i].User
+
+		err := inlineFile(dir, &user.ClientCertificate, &user.ClientCertificateData)
+		if err == nil {
+			err = inlineFile(dir, &user.ClientKey, &user.ClientKeyData)
+		}
+		if err != nil {
+			return Kubeconfig{}, fmt.Errorf("user %s: %w", k.Users[i].Name, err)
+		}
+
+		if user.TokenFile != "" {
+			raw, err := os.ReadFile(ResolvePath(dir, user.TokenFile))
+			if err != nil {
+				return Kubeconfig{}, fmt.Errorf("user %s: %w", k.Users[i].Name, err)
+			}
+
+			user.Token = strings.TrimSpace(string(raw))
+			user.TokenFile = ""
+		}
+	}
+
+	return k, nil
+}
+
+func inlineFile(dir string, path, data *string) error {
+	if *path == "" {
+		return nil
+	}
+
+	raw, err := os.ReadFile(ResolvePath(dir, *path))
+	if err != nil {
+		return err
+	}
+
+	*data = base64.StdEncoding.EncodeToString(raw)
+	*path = ""
+
+	return nil
+}
+
+// Minify returns kubeconfig which contains only given context, current one
+// if name is empty, together with cluster and user it references
+func Minify(k Kubeconfig, name string) (Kubeconfig, error) {
+	if name == "" {
+		name = k.CurrentContext
+	}
+
+	if name == "" {
+		return Kubeconfig{}, WithExitCode(ExitUsage, fmt.Errorf("current-context is not set, specify context explicitly"))
+	}
+
+	context, ok := FindContext(k, name)
+	if !ok {
+		return Kubeconfig{}, WithExitCode(ExitNotFound, fmt.Errorf("context %q not found", name))
+	}
+
+	minified := Kubeconfig{
+		APIVersion:     k.APIVersion,
+		Kind:           k.Kind,
+		Clusters:       []ClusterEntry{},
+		Contexts:       []ContextEntry{context},
+		CurrentContext: context.Name,
+		Users:          []UserEntry{},
+		Preferences:    k.Preferences,
+		Dir:            k.Dir,
+	}
+
+	cluster, ok := FindCluster(k, context.Context.Cluster)
+	if !ok {
+		return Kubeconfig{}, WithExitCode(ExitValidation, fmt.Errorf(
+			"cluster %q of context %q not found", context.Context.Cluster, name,
+		))
+	}
+	minified.Clusters = append(minified.Clusters, cluster)
+
+	if context.Context.User != "" {
+		user, ok := FindUser(k, context.Context.User)
+		if !ok {
+			return Kubeconfig{}, WithExitCode(ExitValidation, fmt.Errorf(
+				"user %q of context %q not found", context.Context.User, name,
+			))
+		}
+		minified.Users = append(minified.Users, user)
+	}
+
+	return minified, nil
+}
+
+// RedactedData replaces certificate and key data hidden by Redact, same as kubectl does
+const RedactedData = "DATA+OMITTED"
+
+// RedactedSecret replaces tokens and passwords hidden by Redact, same as kubectl does
+const RedactedSecret = "REDACTED"
+
+// Redact returns copy of kubeconfig with certificate data, keys, tokens and passwords hidden
+func Redact(k Kubeconfig) Kubeconfig {
+	k.Clusters = append([]ClusterEntry{}, k.Clusters...)
+	k.Users = append([]UserEntry{}, k.Users...)
+
+	redact := func(field *string, replacement string) {
+		if *field != "" {
+			*field = replacement
+		}
+	}
+
+	for i := range k.Clusters {
+		redact(&k.Clusters[i].Cluster.CertificateAuthorityData, RedactedData)
+	}
+
+	for i := range k.Users {
+		user := &k.Users[i].User
+		redact(&user.ClientCertificateData, RedactedData)
+		redact(&user.ClientKeyData, RedactedData)
+		redact(&user.Token, RedactedSecret)
+		redact(&user.Password, RedactedSecret)
+
+		if user.AuthProvider != nil {
+			provider := *user.AuthProvider
+			provider.Config = map[string]string{}
+			for key, value := range user.AuthProvider.Config {
+				if IsSecretField(key) {
+					value = RedactedSecret
+				}
+				provider.Config[key] = value
+			}
+			user.AuthProvider = &provider
+		}
+	}
+
+	return k
+}
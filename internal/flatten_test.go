@@ -0,0 +1,131 @@
+package internal
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestFlatten(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "certs"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "certs", "ca.crt"), []byte("ca"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "certs", "client.crt"), []byte("cert"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "client.key"), []byte("key"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "token"), []byte("secret\n"), 0600))
+
+	config := Kubeconfig{
+		Clusters: []ClusterEntry{
+			{Name: "dev", Cluster: Cluster{Server: "https://dev", CertificateAuthority: "certs/ca.crt"}},
+		},
+		Users: []UserEntry{
+			{Name: "cert", User: User{
+				ClientCertificate: filepath.Join(dir, "certs", "client.crt"),
+				ClientKey:         "client.key",
+			}},
+			{Name: "token", User: User{TokenFile: "./token"}},
+		},
+	}
+
+	flat, err := Flatten(config, dir)
+	require.NoError(t, err)
+
+	require.Equal(t, "certs/ca.crt", config.Clusters[0].Cluster.CertificateAuthority, "input must not be modified")
+	require.Empty(t, FileReferences(&flat))
+
+	encode := base64.StdEncoding.EncodeToString
+	require.Equal(t, encode([]byte("ca")), flat.Clusters[0].Cluster.CertificateAuthorityData)
+	require.Equal(t, encode([]byte("cert")), flat.Users[0].User.ClientCertificateData)
+	require.Equal(t, encode([]byte("key")), flat.Users[0].User.ClientKeyData)
+	require.Equal(t, "secret", flat.Users[1].User.Token)
+
+	raw, err := yaml.Marshal(flat)
+	require.NoError(t, err)
+	require.NotContains(t, string(raw), "certificate-authority:")
+
+	_, err = Flatten(Kubeconfig{Users: []UserEntry{{Name: "x", User: User{ClientKey: "missing"}}}}, dir)
+	require.Error(t, err)
+}
+
+func TestMinify(t *testing.T) {
+	config := Kubeconfig{
+		APIVersion: "v1",
+		Kind:       "Config",
+		Clusters: []ClusterEntry{
+			{Name: "dev", Cluster: Cluster{Server: "https://dev"}},
+			{Name: "prod", Cluster: Cluster{Server: "https://prod"}},
+		},
+		Contexts: []ContextEntry{
+			{Name: "dev", Context: Context{Cluster: "dev", User: "dev"}},
+			{Name: "prod", Context: Context{Cluster: "prod", User: "prod"}},
+			{Name: "broken", Context: Context{Cluster: "missing", User: "dev"}},
+		},
+		Users: []UserEntry{
+			{Name: "dev", User: User{Token: "dev"}},
+			{Name: "prod", User: User{Token: "prod"}},
+		},
+		CurrentContext: "dev",
+	}
+
+	minified, err := Minify(config, "")
+	require.NoError(t, err)
+	require.Equal(t, Kubeconfig{
+		APIVersion:     "v1",
+		Kind:           "Config",
+		Clusters:       []ClusterEntry{config.Clusters[0]},
+		Contexts:       []ContextEntry{config.Contexts[0]},
+		CurrentContext: "dev",
+		Users:          []UserEntry{config.Users[0]},
+	}, minified)
+
+	minified, err = Minify(config, "prod")
+	require.NoError(t, err)
+	require.Equal(t, "prod", minified.CurrentContext)
+	require.Equal(t, "https://prod", minified.Clusters[0].Cluster.Server)
+
+	_, err = Minify(config, "missing")
+	require.Equal(t, ExitNotFound, ExitCode(err))
+
+	_, err = Minify(config, "broken")
+	require.Equal(t, ExitValidation, ExitCode(err))
+}
+
+func TestRedact(t *testing.T) {
+	config := Kubeconfig{
+		Clusters: []ClusterEntry{
+			{Name: "dev", Cluster: Cluster{Server: "https://dev", CertificateAuthorityData: "QUJD"}},
+		},
+		Users: []UserEntry{
+			{Name: "cert", User: User{ClientCertificateData: "QUJD", ClientKeyData: "QUJD"}},
+			{Name: "basic", User: User{Username: "admin", Password: "admin"}},
+			{Name: "token", User: User{Token: "abc", TokenFile: "token"}},
+			{Name: "oidc", User: User{AuthProvider: &AuthProviderConfig{Name: "oidc", Config: map[string]string{
+				"client-id":     "konfig",
+				"client-secret": "secret",
+				"id-token":      "abc",
+			}}}},
+		},
+	}
+
+	redacted := Redact(config)
+	require.Equal(t, "QUJD", config.Users[0].User.ClientKeyData, "input must not be modified")
+
+	require.Equal(t, "https://dev", redacted.Clusters[0].Cluster.Server)
+	require.Equal(t, RedactedData, redacted.Clusters[0].Cluster.CertificateAuthorityData)
+	require.Equal(t, RedactedData, redacted.Users[0].User.ClientCertificateData)
+	require.Equal(t, RedactedData, redacted.Users[0].User.ClientKeyData)
+	require.Equal(t, "admin", redacted.Users[1].User.Username)
+	require.Equal(t, RedactedSecret, redacted.Users[1].User.Password)
+	require.Equal(t, RedactedSecret, redacted.Users[2].User.Token)
+	require.Equal(t, "token", redacted.Users[2].User.TokenFile)
+	require.Equal(t, map[string]string{
+		"client-id":     "konfig",
+		"client-secret": RedactedSecret,
+		"id-token":      RedactedSecret,
+	}, redacted.Users[3].User.AuthProvider.Config)
+	require.Equal(t, "abc", config.Users[3].User.AuthProvider.Config["id-token"], "input must not be modified")
+}
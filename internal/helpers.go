@@ -1,11 +1,14 @@
 package internal
 
 import (
-	"errors"
+	"encoding/json"
 	"fmt"
-	"io"
 	"os"
 	p "path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
@@ -13,7 +16,7 @@ import (
 )
 
 // PrettyPrint print current kubeconfig with colors
-func PrettyPrint(k Kubeconfig) {
+func PrettyPrint(k Kubeconfig) error {
 	magenta := color.New(color.FgMagenta)
 	cyan := color.New(color.FgCyan)
 
@@ -28,7 +31,7 @@ func PrettyPrint(k Kubeconfig) {
 	for _, cluster := range k.Clusters {
 		data, err := yaml.Marshal(cluster)
 		if err != nil {
-			panic(err)
+			return err
 		}
 
 		switch flag {
@@ -46,7 +49,7 @@ func PrettyPrint(k Kubeconfig) {
 	for _, context := range k.Contexts {
 		data, err := yaml.Marshal(context)
 		if err != nil {
-			panic(err)
+			return err
 		}
 
 		switch flag {
@@ -67,7 +70,7 @@ func PrettyPrint(k Kubeconfig) {
 	for _, user := range k.Users {
 		data, err := yaml.Marshal(user)
 		if err != nil {
-			panic(err)
+			return err
 		}
 
 		switch flag {
@@ -83,25 +86,41 @@ func PrettyPrint(k Kubeconfig) {
 	magenta.Print("preferences:")
 	preferences, err := yaml.Marshal(k.Preferences)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
 	fmt.Printf(`%s`, string(preferences))
 
+	return nil
 }
 
 // ReadConf is a helper func for reading kubeconfig files
 func ReadConf(path string) (Kubeconfig, error) {
 	raw, err := os.ReadFile(path)
 	if err != nil {
-		return Kubeconfig{}, fmt.Errorf("cannot open kubeconfig: %s", err)
+		return Kubeconfig{}, fmt.Errorf("cannot open kubeconfig: %w", err)
 	}
 
+	config, err := ParseConf(raw)
+	if err != nil {
+		return Kubeconfig{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	config.Dir, err = filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return Kubeconfig{}, err
+	}
+
+	return config, nil
+}
+
+// ParseConf is a helper func for parsing kubeconfig content
+func ParseConf(raw []byte) (Kubeconfig, error) {
 	config := Kubeconfig{}
 
-	err = yaml.Unmarshal(raw, &config)
+	err := yaml.Unmarshal(raw, &config)
 	if err != nil {
-		return Kubeconfig{}, fmt.Errorf("cannot read kubeconfig: %s", err)
+		return Kubeconfig{}, WithExitCode(ExitValidation, fmt.Errorf("cannot read kubeconfig: %w", err))
 	}
 
 	return config, nil
@@ -111,12 +130,22 @@ func ReadConf(path string) (Kubeconfig, error) {
 // which is assumed to be always correct, in order to continue working, because
 // fails during merge kubeconfigs are assumed as normal usage of program
 func Merge(MainConf, ExtraConf Kubeconfig) (Kubeconfig, error) {
-	if MainConf.APIVersion != MainConf.APIVersion {
-		return MainConf, fmt.Errorf("cannot merge configs with different versions")
+	if MainConf.APIVersion != ExtraConf.APIVersion {
+		return MainConf, WithExitCode(ExitConflict, fmt.Errorf(
+			"cannot merge apiVersion: %s and apiVersion: %s", MainConf.APIVersion, ExtraConf.APIVersion,
+		))
 	}
 
 	if MainConf.Kind != ExtraConf.Kind {
-		return MainConf, fmt.Errorf("cannot merge king: %s and kind: %s", MainConf.Kind, ExtraConf.Kind)
+		return MainConf, WithExitCode(ExitConflict, fmt.Errorf(
+			"cannot merge kind: %s and kind: %s", MainConf.Kind, ExtraConf.Kind,
+		))
+	}
+
+	if MainConf.Dir != "" && ExtraConf.Dir != MainConf.Dir {
+		var warnings []string
+		ExtraConf, warnings = RebasePaths(ExtraConf, MainConf.Dir)
+		Warn(warnings...)
 	}
 
 	clusters := MainConf.Clusters
@@ -136,37 +165,136 @@ func Merge(MainConf, ExtraConf Kubeconfig) (Kubeconfig, error) {
 		CurrentContext: MainConf.CurrentContext,
 		Users:          users,
 		Preferences:    MainConf.Preferences,
+		Dir:            MainConf.Dir,
 	}, nil
 }
 
-// CopyFileContent copies file content from src to dst
-// If file exist, it gives it new uniq name prefix
-func CopyFileContent(src, dst string) (err error) {
-	in, err := os.Open(src)
-	if err != nil {
-		return
+// MergeEntries returns copy of kubeconfig with clusters, contexts and users of extra
+// added. Entries whose names are taken replace existing ones if replace is set,
+// otherwise conflict is reported
+func MergeEntries(k, extra Kubeconfig, replace bool) (Kubeconfig, error) {
+	conflict := func(section, name string) error {
+		return WithExitCode(ExitConflict, fmt.Errorf("%s %q already exists", section, name))
 	}
-	defer func() {
-		closeErr := in.Close()
-		if err == nil {
-			err = closeErr
+
+	k.Clusters = append([]ClusterEntry{}, k.Clusters...)
+	for _, entry := range extra.Clusters {
+		i := indexOf(ClusterNames(k), entry.Name)
+		switch {
+		case i < 0:
+			k.Clusters = append(k.Clusters, entry)
+		case replace:
+			k.Clusters[i] = entry
+		default:
+			return Kubeconfig{}, conflict("cluster", entry.Name)
 		}
-	}()
-	out, err := os.Create(dst)
-	if err != nil {
-		return
 	}
-	defer func() {
-		closeErr := out.Close()
-		if err == nil {
-			err = closeErr
+
+	k.Contexts = append([]ContextEntry{}, k.Contexts...)
+	for _, entry := range extra.Contexts {
+		i := indexOf(ContextNames(k), entry.Name)
+		switch {
+		case i < 0:
+			k.Contexts = append(k.Contexts, entry)
+		case replace:
+			k.Contexts[i] = entry
+		default:
+			return Kubeconfig{}, conflict("context", entry.Name)
 		}
-	}()
-	if _, err = io.Copy(out, in); err != nil {
-		return
 	}
-	err = out.Sync()
-	return
+
+	k.Users = append([]UserEntry{}, k.Users...)
+	for _, entry := range extra.Users {
+		i := indexOf(UserNames(k), entry.Name)
+		switch {
+		case i < 0:
+			k.Users = append(k.Users, entry)
+		case replace:
+			k.Users[i] = entry
+		default:
+			return Kubeconfig{}, conflict("user", entry.Name)
+		}
+	}
+
+	return k, nil
+}
+
+func indexOf(names []string, name string) int {
+	for i, n := range names {
+		if n == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// FindContext returns context with given name
+func FindContext(k Kubeconfig, name string) (ContextEntry, bool) {
+	for _, entry := range k.Contexts {
+		if entry.Name == name {
+			return entry, true
+		}
+	}
+
+	return ContextEntry{}, false
+}
+
+// FindCluster returns cluster with given name
+func FindCluster(k Kubeconfig, name string) (ClusterEntry, bool) {
+	for _, entry := range k.Clusters {
+		if entry.Name == name {
+			return entry, true
+		}
+	}
+
+	return ClusterEntry{}, false
+}
+
+// FindUser returns user with given name
+func FindUser(k Kubeconfig, name string) (UserEntry, bool) {
+	for _, entry := range k.Users {
+		if entry.Name == name {
+			return entry, true
+		}
+	}
+
+	return UserEntry{}, false
+}
+
+// Warn prints warnings to stderr
+func Warn(warnings ...string) {
+	yellow := color.New(color.FgYellow)
+	for _, warning := range warnings {
+		yellow.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+}
+
+// PrintConf prints kubeconfig as yaml
+func PrintConf(k Kubeconfig) error {
+	raw, err := yaml.Marshal(k)
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(string(raw))
+	return nil
+}
+
+// CopyFileContent copies file content from src to dst.
+// dst is replaced atomically and keeps its mode if it already exists
+func CopyFileContent(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	return WriteFileAtomic(dst, data, info.Mode().Perm())
 }
 
 // GetKubeconfigPath returns valid path to kubeconfig according to cmd flags & defaults
@@ -183,35 +311,83 @@ func GetKubeconfigPath(cmd *cobra.Command) (string, error) {
 	return path, nil
 }
 
-// GetBackupFilePath returns valid path to backup according to cmd flags & defaults
+// GetBackupFilePath returns path to custom backup file according to cmd flags.
+// Empty path means backups are kept in default snapshot store
 func GetBackupFilePath(cmd *cobra.Command) (string, error) {
-	path, err := cmd.Flags().GetString(OptionBackup)
+	return cmd.Flags().GetString(OptionBackup)
+}
+
+// GetOutputFilePath returns valid path to output file according to cmd flags & defaults
+func GetOutputFilePath(cmd *cobra.Command) (string, error) {
+	path, err := cmd.Flags().GetString(OptionOutput)
 	if err != nil {
 		return "", err
 	}
 
 	if path == "" {
-		err := os.Mkdir(p.Join(os.Getenv("HOME"), DefaultBackupFolder), os.FileMode(0755))
-		if err != nil && !errors.Is(err, os.ErrExist) {
-			panic(err)
-		}
-
 		return p.Join(os.Getenv("HOME"), DefaultKubeconfigFolder, DefaultKubeconfigFile), nil
 	}
 
 	return path, nil
 }
 
-// GetOutputFilePath returns valid path to output file according to cmd flags & defaults
-func GetOutputFilePath(cmd *cobra.Command) (string, error) {
-	path, err := cmd.Flags().GetString(OptionOutput)
+// GetLockTimeout returns how long to wait for kubeconfig lock according to cmd flags
+func GetLockTimeout(cmd *cobra.Command) (time.Duration, error) {
+	return cmd.Flags().GetDuration(OptionLockTimeout)
+}
+
+// GetOutputFormat returns output format according to cmd flags, checking it is one of supported
+func GetOutputFormat(cmd *cobra.Command, supported ...string) (string, error) {
+	format, err := cmd.Flags().GetString(OptionOutput)
 	if err != nil {
 		return "", err
 	}
 
-	if path == "" {
-		return p.Join(os.Getenv("HOME"), DefaultKubeconfigFolder, DefaultKubeconfigFile), nil
+	for _, s := range supported {
+		if format == s {
+			return format, nil
+		}
 	}
 
-	return path, nil
+	return "", WithExitCode(ExitUsage, fmt.Errorf(
+		"unsupported output format %q, use one of: %s", format, strings.Join(supported, ", "),
+	))
+}
+
+// PrintJSON prints v as indented json
+func PrintJSON(v interface{}) error {
+	raw, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(raw))
+	return nil
+}
+
+// ParseDuration parses duration like time.ParseDuration does,
+// additionally accepting number of days, e.g. "30d"
+func ParseDuration(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	return time.ParseDuration(s)
+}
+
+// HumanDuration formats duration rounded to days, hours or minutes
+func HumanDuration(d time.Duration) string {
+	switch {
+	case d >= 48*time.Hour:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	default:
+		return d.Round(time.Minute).String()
+	}
 }
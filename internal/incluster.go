@@ -0,0 +1,54 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ServiceAccountDir is where service account credentials are mounted into pods
+const ServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// DefaultInClusterName is name of entries created by InClusterConf by default
+const DefaultInClusterName = "in-cluster"
+
+// InClusterConf returns kubeconfig for accessing API server from inside pod the way
+// client-go in-cluster config does: server is taken from KUBERNETES_SERVICE_HOST and
+// KUBERNETES_SERVICE_PORT, credentials from service account mount under root.
+// Token is referenced by tokenFile, so rotated tokens are picked up
+func InClusterConf(root, name string) (Kubeconfig, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return Kubeconfig{}, WithExitCode(ExitNotFound, errors.New(
+			"KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT are not set, not running inside cluster?",
+		))
+	}
+
+	dir, err := filepath.Abs(filepath.Join(root, ServiceAccountDir))
+	if err != nil {
+		return Kubeconfig{}, err
+	}
+
+	tokenFile := filepath.Join(dir, "token")
+	if _, err := os.Stat(tokenFile); err != nil {
+		return Kubeconfig{}, fmt.Errorf("cannot read service account token: %w", err)
+	}
+
+	cluster := Cluster{Server: "https://" + net.JoinHostPort(host, port)}
+
+	caFile := filepath.Join(dir, "ca.crt")
+	if _, err := os.Stat(caFile); err == nil {
+		cluster.CertificateAuthority = caFile
+	}
+
+	namespace := ""
+	raw, err := os.ReadFile(filepath.Join(dir, "namespace"))
+	if err == nil {
+		namespace = strings.TrimSpace(string(raw))
+	}
+
+	return SingleContextConf(name, cluster, User{TokenFile: tokenFile}, namespace), nil
+}
@@ -0,0 +1,37 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInClusterConf(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, ServiceAccountDir)
+	require.NoError(t, os.MkdirAll(dir, 0700))
+
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	t.Setenv("KUBERNETES_SERVICE_PORT", "443")
+
+	_, err := InClusterConf(root, DefaultInClusterName)
+	require.Equal(t, ExitNotFound, ExitCode(err))
+
+	t.Setenv("KUBERNETES_SERVICE_HOST", "fd00::1")
+
+	_, err = InClusterConf(root, DefaultInClusterName)
+	require.Equal(t, ExitNotFound, ExitCode(err))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "token"), []byte("token"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ca.crt"), []byte("ca"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "namespace"), []byte("ci\n"), 0600))
+
+	config, err := InClusterConf(root, DefaultInClusterName)
+	require.NoError(t, err)
+	require.Equal(t, SingleContextConf(DefaultInClusterName, Cluster{
+		Server:               "https://[fd00::1]:443",
+		CertificateAuthority: filepath.Join(dir, "ca.crt"),
+	}, User{TokenFile: filepath.Join(dir, "token")}, "ci"), config)
+}
@@ -0,0 +1,225 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// LockSuffix is appended to kubeconfig path to get name of lock file,
+// the same way client-go (and so kubectl) does it
+const LockSuffix = ".lock"
+
+// DefaultLockTimeout is how long konfig waits for other programs to release kubeconfig
+const DefaultLockTimeout = 10 * time.Second
+
+// StaleLockAge is age after which lock file is assumed to be left by crashed program.
+// client-go holds lock only while writing kubeconfig, so it never lives that long
+const StaleLockAge = time.Minute
+
+// lockRetryInterval is interval between attempts to acquire lock
+const lockRetryInterval = 100 * time.Millisecond
+
+// ErrLockTimeout is returned when lock is not released by its owner in time
+var ErrLockTimeout = errors.New("timed out waiting for lock")
+
+// ErrStaleLock is returned when lock file is left by crashed program
+var ErrStaleLock = errors.New("stale lock")
+
+// LockFile acquires lock on file at path by creating <path>.lock, waiting for
+// other owners up to timeout. Returned func releases the lock
+func LockFile(path string, timeout time.Duration) (func() error, error) {
+	lock := path + LockSuffix
+
+	err := os.MkdirAll(filepath.Dir(lock), os.FileMode(0755))
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL, 0)
+		if err == nil {
+			err = f.Close()
+			if err != nil {
+				return nil, err
+			}
+
+			return func() error {
+				return os.Remove(lock)
+			}, nil
+		}
+
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("cannot lock %s: %w", path, err)
+		}
+
+		info, err := os.Stat(lock)
+		if err == nil && time.Since(info.ModTime()) > StaleLockAge {
+			return nil, fmt.Errorf(
+				"%w: %s was created %s ago, remove it if no kubectl or konfig is running",
+				ErrStaleLock, lock, time.Since(info.ModTime()).Round(time.Second),
+			)
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%w: %s is held by another program", ErrLockTimeout, lock)
+		}
+
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// WithLock runs fn while holding lock on file at path
+func WithLock(path string, timeout time.Duration, fn func() error) (err error) {
+	unlock, err := LockFile(path, timeout)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		unlockErr := unlock()
+		if err == nil {
+			err = unlockErr
+		}
+	}()
+
+	return fn()
+}
+
+// WithLocks runs fn while holding locks on files at paths. Locks are taken in
+// sorted order, so two programs locking the same files cannot deadlock
+func WithLocks(paths []string, timeout time.Duration, fn func() error) error {
+	unique := map[string]bool{}
+	for _, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		unique[abs] = true
+	}
+
+	sorted := []string{}
+	for path := range unique {
+		sorted = append(sorted, path)
+	}
+	sort.Strings(sorted)
+
+	locked := fn
+	for i := len(sorted) - 1; i >= 0; i-- {
+		path, inner := sorted[i], locked
+		locked = func() error {
+			return WithLock(path, timeout, inner)
+		}
+	}
+
+	return locked()
+}
+
+// UpdateConf applies update to kubeconfig at src and stores result to dst while holding
+// locks on both. Kubeconfig is read under lock, so changes made by other programs are not lost.
+// Only changed fields are rewritten, so comments and fields konfig does not know are kept
+func UpdateConf(src, dst string, timeout time.Duration, update func(Kubeconfig) (Kubeconfig, error)) error {
+	return WithLocks([]string{src, dst}, timeout, func() error {
+		raw, err := os.ReadFile(src)
+		if err != nil {
+			return fmt.Errorf("cannot open kubeconfig: %w", err)
+		}
+
+		return patchConf(src, dst, raw, Kubeconfig{}, update)
+	})
+}
+
+// UpdateOrCreateConf is UpdateConf for kubeconfig at path, which is created if it
+// does not exist yet, like kubectl does
+func UpdateOrCreateConf(path string, timeout time.Duration, update func(Kubeconfig) (Kubeconfig, error)) error {
+	_, err := os.Stat(path)
+	if !errors.Is(err, os.ErrNotExist) {
+		return UpdateConf(path, path, timeout, update)
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), os.FileMode(0700))
+	if err != nil {
+		return err
+	}
+
+	return WithLock(path, timeout, func() error {
+		// file may be created by someone else while lock was awaited
+		raw, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("cannot open kubeconfig: %w", err)
+		}
+
+		return patchConf(path, path, raw, Kubeconfig{APIVersion: "v1", Kind: "Config"}, update)
+	})
+}
+
+// patchConf applies update to raw kubeconfig read from src and stores result to dst.
+// Missing or empty raw content gives kubeconfig empty
+func patchConf(src, dst string, raw []byte, empty Kubeconfig, update func(Kubeconfig) (Kubeconfig, error)) error {
+	parse := func() (Kubeconfig, error) {
+		if len(raw) == 0 {
+			return empty, nil
+		}
+
+		config, err := ParseConf(raw)
+		if err != nil {
+			return Kubeconfig{}, fmt.Errorf("%s: %w", src, err)
+		}
+
+		return config, nil
+	}
+
+	dir, err := filepath.Abs(filepath.Dir(src))
+	if err != nil {
+		return err
+	}
+
+	before, err := parse()
+	if err != nil {
+		return err
+	}
+	before.Dir = dir
+
+	// update may change entries in place, so it gets its own copy
+	config, err := parse()
+	if err != nil {
+		return err
+	}
+	config.Dir = dir
+
+	config, err = update(config)
+	if err != nil {
+		return err
+	}
+
+	return WritePatchedConf(dst, raw, before, config)
+}
+
+// UpdateRawConf applies update to content of kubeconfig at path while holding lock on it.
+// Unlike UpdateConf, it works with raw content, so comments and formatting can be kept.
+// Missing kubeconfig is treated as empty one
+func UpdateRawConf(path string, timeout time.Duration, update func([]byte) ([]byte, error)) error {
+	return WithLock(path, timeout, func() error {
+		raw, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("cannot open kubeconfig: %w", err)
+		}
+
+		raw, err = update(raw)
+		if err != nil {
+			return err
+		}
+
+		return WriteFileAtomic(path, raw, os.FileMode(0600))
+	})
+}
+
+// SaveConf stores kubeconfig at path while holding lock on it
+func SaveConf(path string, timeout time.Duration, k Kubeconfig) error {
+	return WithLock(path, timeout, func() error {
+		return WriteConf(path, k)
+	})
+}
@@ -0,0 +1,165 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+
+	unlock, err := LockFile(path, time.Second)
+	require.NoError(t, err)
+	require.FileExists(t, path+LockSuffix)
+
+	_, err = LockFile(path, 200*time.Millisecond)
+	require.ErrorIs(t, err, ErrLockTimeout)
+
+	require.NoError(t, unlock())
+	require.NoFileExists(t, path+LockSuffix)
+
+	unlock, err = LockFile(path, time.Second)
+	require.NoError(t, err)
+	require.NoError(t, unlock())
+}
+
+func TestStaleLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(path+LockSuffix, nil, 0600))
+
+	old := time.Now().Add(-2 * StaleLockAge)
+	require.NoError(t, os.Chtimes(path+LockSuffix, old, old))
+
+	_, err := LockFile(path, time.Second)
+	require.ErrorIs(t, err, ErrStaleLock)
+}
+
+func TestUpdateConfWaitsForLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(path, []byte("kind: Config\n"), 0600))
+
+	unlock, err := LockFile(path, time.Second)
+	require.NoError(t, err)
+
+	go func() {
+		// another program updates kubeconfig while holding the lock
+		time.Sleep(200 * time.Millisecond)
+		_ = os.WriteFile(path, []byte("kind: Config\ncurrent-context: other\n"), 0600)
+		_ = unlock()
+	}()
+
+	err = UpdateConf(path, path, 5*time.Second, func(k Kubeconfig) (Kubeconfig, error) {
+		require.Equal(t, "other", k.CurrentContext)
+		k.APIVersion = "v1"
+		return k, nil
+	})
+	require.NoError(t, err)
+
+	config, err := ReadConf(path)
+	require.NoError(t, err)
+	require.Equal(t, "v1", config.APIVersion)
+	require.Equal(t, "other", config.CurrentContext)
+	require.NoFileExists(t, path+LockSuffix)
+}
+
+func TestUpdateConfLocksSource(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+	require.NoError(t, os.WriteFile(src, []byte("kind: Config\n"), 0600))
+
+	unlock, err := LockFile(src, time.Second)
+	require.NoError(t, err)
+
+	err = UpdateConf(src, dst, 200*time.Millisecond, func(k Kubeconfig) (Kubeconfig, error) {
+		return k, nil
+	})
+	require.ErrorIs(t, err, ErrLockTimeout)
+	require.NoFileExists(t, dst+LockSuffix)
+	require.NoError(t, unlock())
+
+	require.NoError(t, UpdateConf(src, dst, time.Second, func(k Kubeconfig) (Kubeconfig, error) {
+		return k, nil
+	}))
+	require.FileExists(t, dst)
+	require.NoFileExists(t, src+LockSuffix)
+}
+
+// unknownFieldsConf has comments and fields konfig does not model exactly,
+// which must survive every command changing kubeconfig
+const unknownFieldsConf = `# managed by hand
+apiVersion: v1
+kind: Config
+clusters:
+- name: prod
+  cluster:
+    server: https://prod
+    proxy-url: http://proxy:3128 # office proxy
+    tls-server-name: prod.internal
+- name: dev
+  cluster:
+    server: https://dev
+contexts:
+- name: prod
+  context:
+    cluster: prod
+    user: oidc
+    extensions:
+    - name: tool
+      extension:
+        color: red
+- name: dev
+  context:
+    cluster: dev
+    user: dev
+current-context: prod
+users:
+- name: oidc
+  user:
+    auth-provider:
+      name: oidc
+      config:
+        client-id: konfig
+        idp-issuer-url: https://issuer
+- name: dev
+  user:
+    token: abc
+`
+
+// requireUnknownFieldsKept checks that text of prod context, its cluster and user
+// of unknownFieldsConf is still in raw kubeconfig
+func requireUnknownFieldsKept(t *testing.T, raw []byte) {
+	require.Contains(t, string(raw), "# managed by hand\n")
+	require.Contains(t, string(raw), "    proxy-url: http://proxy:3128 # office proxy\n    tls-server-name: prod.internal\n")
+	require.Contains(t, string(raw), "    extensions:\n    - name: tool\n      extension:\n        color: red\n")
+	require.Contains(t, string(raw), `    auth-provider:
+      name: oidc
+      config:
+        client-id: konfig
+        idp-issuer-url: https://issuer
+`)
+}
+
+func TestUpdateConfKeepsUnknownFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(path, []byte(unknownFieldsConf), 0600))
+
+	// split --delete removes split contexts this way
+	require.NoError(t, UpdateConf(path, path, time.Second, func(k Kubeconfig) (Kubeconfig, error) {
+		return RemoveContexts(k, []string{"dev"}, false), nil
+	}))
+
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	requireUnknownFieldsKept(t, raw)
+
+	config, err := ReadConf(path)
+	require.NoError(t, err)
+	require.Len(t, config.Contexts, 1)
+	require.Len(t, config.Clusters, 1)
+	require.Len(t, config.Users, 1)
+}
@@ -0,0 +1,105 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// unsafeFileChars matches characters which are not kept in generated file names
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// FileReferences returns pointers to every non-empty field of kubeconfig
+// which references a file on disk, so callers can both read and rewrite them.
+// Exec plugin commands are included if they are paths, not names looked up in PATH
+func FileReferences(k *Kubeconfig) []*string {
+	refs := []*string{}
+
+	add := func(ref *string) {
+		if *ref != "" {
+			refs = append(refs, ref)
+		}
+	}
+
+	for i := range k.Clusters {
+		add(&k.Clusters[i].Cluster.CertificateAuthority)
+	}
+
+	for i := range k.Users {
+		add(&k.Users[i].User.ClientCertificate)
+		add(&k.Users[i].User.ClientKey)
+		add(&k.Users[i].User.TokenFile)
+
+		if exec := k.Users[i].User.Exec; exec != nil && strings.ContainsAny(exec.Command, "/"+string(filepath.Separator)) {
+			add(&exec.Command)
+		}
+	}
+
+	return refs
+}
+
+// ResolvePath returns path as kubectl sees it: relative paths in kubeconfig
+// are resolved against the folder the kubeconfig is stored in
+func ResolvePath(dir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(dir, path)
+}
+
+// RebasePaths returns copy of kubeconfig moved to folder dir: relative file references
+// are rewritten to keep pointing to the same files. Returned warnings describe
+// references which could not be rewritten or point to missing files
+func RebasePaths(k Kubeconfig, dir string) (Kubeconfig, []string) {
+	k.Clusters = append([]ClusterEntry{}, k.Clusters...)
+	k.Users = append([]UserEntry{}, k.Users...)
+
+	for i := range k.Users {
+		if exec := k.Users[i].User.Exec; exec != nil {
+			copied := *exec
+			k.Users[i].User.Exec = &copied
+		}
+	}
+
+	warnings := []string{}
+
+	for _, ref := range FileReferences(&k) {
+		if filepath.IsAbs(*ref) {
+			continue
+		}
+
+		if k.Dir == "" {
+			warnings = append(warnings, fmt.Sprintf("cannot tell which file relative path %s points to", *ref))
+			continue
+		}
+
+		target := filepath.Join(k.Dir, *ref)
+		if _, err := os.Stat(target); err != nil {
+			warnings = append(warnings, fmt.Sprintf("referenced file %s does not exist", target))
+		}
+
+		rel, err := filepath.Rel(dir, target)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf(
+				"cannot reference %s relative to %s, using absolute path", target, dir,
+			))
+			rel = target
+		}
+
+		*ref = rel
+	}
+
+	if k.Dir != "" {
+		k.Dir = dir
+	}
+
+	return k, warnings
+}
+
+// SafeFileName turns kubeconfig entry name, e.g. EKS cluster ARN, into file name
+func SafeFileName(name string) string {
+	return unsafeFileChars.ReplaceAllString(name, "_")
+}
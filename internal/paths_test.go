@@ -0,0 +1,87 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRebasePaths(t *testing.T) {
+	root := t.TempDir()
+	other := filepath.Join(root, "other")
+	kube := filepath.Join(root, "home", ".kube")
+
+	require.NoError(t, os.MkdirAll(filepath.Join(other, "certs"), 0700))
+	require.NoError(t, os.MkdirAll(kube, 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(other, "certs", "key.pem"), nil, 0600))
+
+	config := Kubeconfig{
+		Clusters: []ClusterEntry{
+			{Name: "abs", Cluster: Cluster{CertificateAuthority: filepath.Join(root, "ca.crt")}},
+		},
+		Users: []UserEntry{
+			{Name: "rel", User: User{ClientKey: "certs/key.pem"}},
+			{Name: "missing", User: User{TokenFile: "token"}},
+			{Name: "exec", User: User{Exec: &ExecConfig{Command: filepath.Join("bin", "kubelogin")}}},
+			{Name: "path", User: User{Exec: &ExecConfig{Command: "kubelogin"}}},
+		},
+		Dir: other,
+	}
+
+	rebased, warnings := RebasePaths(config, kube)
+	require.Equal(t, kube, rebased.Dir)
+	require.Equal(t, "certs/key.pem", config.Users[0].User.ClientKey, "input must not be modified")
+
+	require.Equal(t, filepath.Join(root, "ca.crt"), rebased.Clusters[0].Cluster.CertificateAuthority)
+	require.Equal(t, filepath.Join("..", "..", "other", "certs", "key.pem"), rebased.Users[0].User.ClientKey)
+	require.Equal(t, filepath.Join(kube, rebased.Users[0].User.ClientKey), filepath.Join(other, "certs", "key.pem"))
+	require.Equal(t, filepath.Join("..", "..", "other", "bin", "kubelogin"), rebased.Users[2].User.Exec.Command)
+	require.Equal(t, filepath.Join("bin", "kubelogin"), config.Users[2].User.Exec.Command, "input must not be modified")
+	require.Equal(t, "kubelogin", rebased.Users[3].User.Exec.Command)
+	require.Len(t, warnings, 2)
+	require.Contains(t, warnings[0], "token")
+	require.Contains(t, warnings[1], "kubelogin")
+
+	config.Dir = ""
+	_, warnings = RebasePaths(config, kube)
+	require.Len(t, warnings, 3)
+}
+
+func TestMergeRebasesPaths(t *testing.T) {
+	root := t.TempDir()
+	mainDir := filepath.Join(root, "main")
+	extraDir := filepath.Join(root, "extra")
+	require.NoError(t, os.MkdirAll(mainDir, 0700))
+	require.NoError(t, os.MkdirAll(filepath.Join(extraDir, "certs"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(extraDir, "certs", "key.pem"), nil, 0600))
+
+	require.NoError(t, os.WriteFile(filepath.Join(mainDir, "config"), []byte("kind: Config\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(extraDir, "config"), []byte(`
+kind: Config
+users:
+- name: extra
+  user:
+    client-key: certs/key.pem
+`), 0600))
+
+	mainConf, err := ReadConf(filepath.Join(mainDir, "config"))
+	require.NoError(t, err)
+	extraConf, err := ReadConf(filepath.Join(extraDir, "config"))
+	require.NoError(t, err)
+
+	merged, err := Merge(mainConf, extraConf)
+	require.NoError(t, err)
+	require.Equal(t, mainDir, merged.Dir)
+	require.Equal(t, filepath.Join("..", "extra", "certs", "key.pem"), merged.Users[0].User.ClientKey)
+
+	// storing to another folder keeps references valid as well
+	output := filepath.Join(root, "output", "config")
+	require.NoError(t, os.MkdirAll(filepath.Dir(output), 0700))
+	require.NoError(t, WriteConf(output, merged))
+
+	stored, err := ReadConf(output)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join("..", "extra", "certs", "key.pem"), stored.Users[0].User.ClientKey)
+}
@@ -0,0 +1,241 @@
+package internal
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/fatih/color"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// Reasons to prune kubeconfig entries
+const (
+	// PruneUnused means cluster or user is not referenced by any remaining context
+	PruneUnused = "unused"
+	// PruneBroken means context references missing cluster or user
+	PruneBroken = "broken"
+	// PruneDuplicate means cluster or user has the same content as another one
+	PruneDuplicate = "duplicate"
+	// PruneExpired means client certificate of context user has expired
+	PruneExpired = "expired"
+)
+
+// PruneCandidate is kubeconfig entry which can be safely removed
+type PruneCandidate struct {
+	Section string `json:"section"`
+	Name    string `json:"name"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+	// DuplicateOf is name of entry references to duplicate are moved to
+	DuplicateOf string `json:"duplicateOf,omitempty"`
+}
+
+// FindPrunable finds clusters and users duplicating other entries, contexts which
+// are broken or use expired client certificates, and clusters and users no remaining
+// context uses. Duplicates are found by comparing every field in raw kubeconfig k was
+// parsed from, including ones konfig does not know. Relative paths are resolved against dir
+func FindPrunable(k Kubeconfig, raw []byte, dir string) []PruneCandidate {
+	candidates := []PruneCandidate{}
+
+	clusterNames := []string{}
+	for _, entry := range k.Clusters {
+		clusterNames = append(clusterNames, entry.Name)
+	}
+
+	clusterAliases := map[string]string{}
+	clusters := entryContents(raw, "clusters", clusterNames)
+	for i, entry := range k.Clusters {
+		for j, other := range k.Clusters[:i] {
+			if _, ok := clusterAliases[other.Name]; !ok && clusters != nil && reflect.DeepEqual(clusters[i], clusters[j]) {
+				clusterAliases[entry.Name] = other.Name
+				candidates = append(candidates, PruneCandidate{
+					Section: "cluster", Name: entry.Name, Reason: PruneDuplicate, DuplicateOf: other.Name,
+					Message: fmt.Sprintf("same as cluster %s", other.Name),
+				})
+				break
+			}
+		}
+	}
+
+	userNames := []string{}
+	for _, entry := range k.Users {
+		userNames = append(userNames, entry.Name)
+	}
+
+	userAliases := map[string]string{}
+	users := entryContents(raw, "users", userNames)
+	for i, entry := range k.Users {
+		for j, other := range k.Users[:i] {
+			if _, ok := userAliases[other.Name]; !ok && users != nil && reflect.DeepEqual(users[i], users[j]) {
+				userAliases[entry.Name] = other.Name
+				candidates = append(candidates, PruneCandidate{
+					Section: "user", Name: entry.Name, Reason: PruneDuplicate, DuplicateOf: other.Name,
+					Message: fmt.Sprintf("same as user %s", other.Name),
+				})
+				break
+			}
+		}
+	}
+
+	expiredUsers := map[string]string{}
+	for _, info := range InspectCertificates(k, dir) {
+		if info.Section == "user" && info.ExpiresWithin(0) {
+			expiredUsers[info.Name] = fmt.Sprintf("client certificate of user %s expired at %s",
+				info.Name, info.NotAfter.Local().Format("2006-01-02"))
+		}
+	}
+
+	usedClusters := map[string]bool{}
+	usedUsers := map[string]bool{}
+
+	for _, entry := range k.Contexts {
+		_, clusterFound := FindCluster(k, entry.Context.Cluster)
+		_, userFound := FindUser(k, entry.Context.User)
+
+		switch {
+		case !clusterFound:
+			candidates = append(candidates, PruneCandidate{
+				Section: "context", Name: entry.Name, Reason: PruneBroken,
+				Message: fmt.Sprintf("cluster %q not found", entry.Context.Cluster),
+			})
+		case entry.Context.User != "" && !userFound:
+			candidates = append(candidates, PruneCandidate{
+				Section: "context", Name: entry.Name, Reason: PruneBroken,
+				Message: fmt.Sprintf("user %q not found", entry.Context.User),
+			})
+		case expiredUsers[entry.Context.User] != "":
+			candidates = append(candidates, PruneCandidate{
+				Section: "context", Name: entry.Name, Reason: PruneExpired,
+				Message: expiredUsers[entry.Context.User],
+			})
+		default:
+			usedClusters[aliasOf(clusterAliases, entry.Context.Cluster)] = true
+			usedUsers[aliasOf(userAliases, entry.Context.User)] = true
+		}
+	}
+
+	for _, entry := range k.Clusters {
+		if _, ok := clusterAliases[entry.Name]; !ok && !usedClusters[entry.Name] {
+			candidates = append(candidates, PruneCandidate{
+				Section: "cluster", Name: entry.Name, Reason: PruneUnused,
+				Message: "not used by any context",
+			})
+		}
+	}
+
+	for _, entry := range k.Users {
+		if _, ok := userAliases[entry.Name]; !ok && !usedUsers[entry.Name] {
+			candidates = append(candidates, PruneCandidate{
+				Section: "user", Name: entry.Name, Reason: PruneUnused,
+				Message: "not used by any context",
+			})
+		}
+	}
+
+	return candidates
+}
+
+// entryContents returns contents of entries of named list section of raw kubeconfig,
+// like cluster of every clusters entry, with all their fields. Nil is returned if
+// entries do not have given names, e.g. when kubeconfig was changed meanwhile
+func entryContents(raw []byte, section string, names []string) []interface{} {
+	var doc yamlv3.Node
+	if yamlv3.Unmarshal(raw, &doc) != nil || len(doc.Content) == 0 {
+		return nil
+	}
+
+	list := lookupMapping(doc.Content[0], section)
+	if list == nil || list.Kind != yamlv3.SequenceNode || len(list.Content) != len(names) {
+		return nil
+	}
+
+	contents := []interface{}{}
+	for i, entry := range list.Content {
+		if entryNodeName(entry) != names[i] {
+			return nil
+		}
+
+		var content interface{}
+		if value := lookupMapping(entry, namedSections[section].key); value != nil && value.Decode(&content) != nil {
+			return nil
+		}
+
+		contents = append(contents, content)
+	}
+
+	return contents
+}
+
+func aliasOf(aliases map[string]string, name string) string {
+	if alias, ok := aliases[name]; ok {
+		return alias
+	}
+
+	return name
+}
+
+// Prune returns copy of kubeconfig without given entries. Contexts referencing
+// removed duplicates are repointed to entries they duplicate
+func Prune(k Kubeconfig, candidates []PruneCandidate) Kubeconfig {
+	remove := map[string]map[string]bool{"cluster": {}, "context": {}, "user": {}}
+	clusterAliases := map[string]string{}
+	userAliases := map[string]string{}
+
+	for _, candidate := range candidates {
+		remove[candidate.Section][candidate.Name] = true
+
+		if candidate.Reason != PruneDuplicate {
+			continue
+		}
+
+		switch candidate.Section {
+		case "cluster":
+			clusterAliases[candidate.Name] = candidate.DuplicateOf
+		case "user":
+			userAliases[candidate.Name] = candidate.DuplicateOf
+		}
+	}
+
+	clusters := []ClusterEntry{}
+	for _, entry := range k.Clusters {
+		if !remove["cluster"][entry.Name] {
+			clusters = append(clusters, entry)
+		}
+	}
+
+	contexts := []ContextEntry{}
+	for _, entry := range k.Contexts {
+		if !remove["context"][entry.Name] {
+			entry.Context.Cluster = aliasOf(clusterAliases, entry.Context.Cluster)
+			entry.Context.User = aliasOf(userAliases, entry.Context.User)
+			contexts = append(contexts, entry)
+		}
+	}
+
+	users := []UserEntry{}
+	for _, entry := range k.Users {
+		if !remove["user"][entry.Name] {
+			users = append(users, entry)
+		}
+	}
+
+	k.Clusters = clusters
+	k.Contexts = contexts
+	k.Users = users
+
+	if remove["context"][k.CurrentContext] {
+		k.CurrentContext = ""
+	}
+
+	return k
+}
+
+// PrintPruneCandidates prints entries to prune with reasons
+func PrintPruneCandidates(candidates []PruneCandidate) {
+	yellow := color.New(color.FgYellow)
+
+	for _, candidate := range candidates {
+		yellow.Printf("%-9s ", candidate.Reason)
+		fmt.Printf("%s %s: %s\n", candidate.Section, candidate.Name, candidate.Message)
+	}
+}
@@ -0,0 +1,116 @@
+package internal
+
+import (
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestPrune(t *testing.T) {
+	expired, _ := testCertificate(t, pkix.Name{CommonName: "old"}, time.Now().Add(-time.Hour))
+	valid, _ := testCertificate(t, pkix.Name{CommonName: "new"}, time.Now().Add(time.Hour))
+
+	config := Kubeconfig{
+		Clusters: []ClusterEntry{
+			{Name: "prod", Cluster: Cluster{Server: "https://prod"}},
+			{Name: "prod-copy", Cluster: Cluster{Server: "https://prod"}},
+			{Name: "legacy", Cluster: Cluster{Server: "https://legacy"}},
+			{Name: "stale", Cluster: Cluster{Server: "https://stale"}},
+		},
+		Contexts: []ContextEntry{
+			{Name: "prod", Context: Context{Cluster: "prod-copy", User: "admin"}},
+			{Name: "broken", Context: Context{Cluster: "missing", User: "admin"}},
+			{Name: "old", Context: Context{Cluster: "stale", User: "old"}},
+		},
+		Users: []UserEntry{
+			{Name: "admin", User: User{ClientCertificateData: base64.StdEncoding.EncodeToString(valid)}},
+			{Name: "old", User: User{ClientCertificateData: base64.StdEncoding.EncodeToString(expired)}},
+			{Name: "nobody", User: User{Token: "abc"}},
+		},
+		CurrentContext: "old",
+	}
+
+	raw, err := yaml.Marshal(config)
+	require.NoError(t, err)
+
+	candidates := FindPrunable(config, raw, t.TempDir())
+
+	reasons := map[string]string{}
+	for _, candidate := range candidates {
+		reasons[candidate.Section+" "+candidate.Name] = candidate.Reason
+	}
+
+	require.Equal(t, map[string]string{
+		"cluster prod-copy": PruneDuplicate,
+		"context broken":    PruneBroken,
+		"context old":       PruneExpired,
+		"cluster legacy":    PruneUnused,
+		"cluster stale":     PruneUnused,
+		"user old":          PruneUnused,
+		"user nobody":       PruneUnused,
+	}, reasons)
+
+	result := Prune(config, candidates)
+	require.Equal(t, []ClusterEntry{config.Clusters[0]}, result.Clusters)
+	require.Equal(t, []ContextEntry{{Name: "prod", Context: Context{Cluster: "prod", User: "admin"}}}, result.Contexts)
+	require.Equal(t, []UserEntry{config.Users[0]}, result.Users)
+	require.Empty(t, result.CurrentContext)
+
+	raw, err = yaml.Marshal(result)
+	require.NoError(t, err)
+	require.Empty(t, FindPrunable(result, raw, t.TempDir()))
+}
+
+func TestPruneComparesAllFields(t *testing.T) {
+	raw := []byte(`
+clusters:
+- name: a
+  cluster:
+    server: https://prod
+    unknown-field: a
+- name: b
+  cluster:
+    server: "https://prod"
+    unknown-field: b
+contexts:
+- name: a
+  context: {cluster: a, user: a}
+- name: b
+  context: {cluster: b, user: b}
+users:
+- name: a
+  user:
+    auth-provider:
+      name: oidc
+      config: {client-id: a}
+    unknown-field: x
+- name: b
+  user:
+    auth-provider:
+      name: oidc
+      config: {client-id: b}
+    unknown-field: x
+- name: c
+  user:
+    unknown-field: x
+    auth-provider:
+      config: {client-id: a}
+      name: oidc
+`)
+	config, err := ParseConf(raw)
+	require.NoError(t, err)
+
+	candidates := FindPrunable(config, raw, t.TempDir())
+	require.Equal(t, []PruneCandidate{
+		{Section: "user", Name: "c", Reason: PruneDuplicate, DuplicateOf: "a", Message: "same as user a"},
+	}, candidates)
+
+	// raw content of another kubeconfig gives no duplicates
+	for _, candidate := range FindPrunable(config, []byte("users: [{name: x}]"), t.TempDir()) {
+		require.NotEqual(t, PruneDuplicate, candidate.Reason)
+	}
+}
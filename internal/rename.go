@@ -0,0 +1,216 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+
+	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// Rename returns copy of kubeconfig with entries of section, which is cluster,
+// context or user, renamed according to renames mapping old names to new ones.
+// References of contexts and current-context are updated. Renames are applied
+// at once, so names may be swapped, but resulting names must be unique
+func Rename(k Kubeconfig, section string, renames map[string]string) (Kubeconfig, error) {
+	var names []string
+
+	switch section {
+	case "cluster":
+		names = ClusterNames(k)
+	case "context":
+		names = ContextNames(k)
+	case "user":
+		names = UserNames(k)
+	default:
+		return Kubeconfig{}, WithExitCode(ExitUsage, fmt.Errorf("unknown section %q", section))
+	}
+
+	exists := map[string]bool{}
+	for _, name := range names {
+		exists[name] = true
+	}
+
+	for old := range renames {
+		if !exists[old] {
+			return Kubeconfig{}, WithExitCode(ExitNotFound, fmt.Errorf("%s %q not found", section, old))
+		}
+	}
+
+	taken := map[string]string{}
+	for _, name := range names {
+		renamed := renameOf(renames, name)
+
+		if other, ok := taken[renamed]; ok {
+			return Kubeconfig{}, WithExitCode(ExitConflict, fmt.Errorf(
+				"%s name %q would be used by both %q and %q", section, renamed, other, name,
+			))
+		}
+
+		taken[renamed] = name
+	}
+
+	k.Clusters = append([]ClusterEntry{}, k.Clusters...)
+	k.Contexts = append([]ContextEntry{}, k.Contexts...)
+	k.Users = append([]UserEntry{}, k.Users...)
+
+	switch section {
+	case "cluster":
+		for i := range k.Clusters {
+			k.Clusters[i].Name = renameOf(renames, k.Clusters[i].Name)
+		}
+
+		for i := range k.Contexts {
+			k.Contexts[i].Context.Cluster = renameOf(renames, k.Contexts[i].Context.Cluster)
+		}
+	case "context":
+		for i := range k.Contexts {
+			k.Contexts[i].Name = renameOf(renames, k.Contexts[i].Name)
+		}
+
+		if k.CurrentContext != "" {
+			k.CurrentContext = renameOf(renames, k.CurrentContext)
+		}
+	case "user":
+		for i := range k.Users {
+			k.Users[i].Name = renameOf(renames, k.Users[i].Name)
+		}
+
+		for i := range k.Contexts {
+			if k.Contexts[i].Context.User != "" {
+				k.Contexts[i].Context.User = renameOf(renames, k.Contexts[i].Context.User)
+			}
+		}
+	}
+
+	return k, nil
+}
+
+// RenameConf is Rename for raw kubeconfig. Only names and references to them are
+// changed, so comments, formatting and fields konfig does not know are kept
+func RenameConf(raw []byte, section string, renames map[string]string) ([]byte, error) {
+	config, err := ParseConf(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	expected, err := Rename(config, section, renames)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := parseDocument(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	rename := func(node *yamlv3.Node) {
+		if node == nil || node.Kind != yamlv3.ScalarNode {
+			return
+		}
+
+		if renamed := renameOf(renames, node.Value); renamed != node.Value {
+			node.Value, node.Tag = renamed, "!!str"
+		}
+	}
+
+	entries := func(section string) []*yamlv3.Node {
+		if list := lookupMapping(d.root, section); list != nil {
+			return list.Content
+		}
+		return nil
+	}
+
+	for _, entry := range entries(section + "s") {
+		rename(lookupMapping(entry, "name"))
+	}
+
+	switch section {
+	case "cluster", "user":
+		for _, entry := range entries("contexts") {
+			rename(lookupMapping(lookupMapping(entry, "context"), section))
+		}
+	case "context":
+		rename(lookupMapping(d.root, "current-context"))
+	}
+
+	result, err := d.encode()
+	if err != nil {
+		return nil, err
+	}
+
+	renamed, err := ParseConf(result)
+	if err != nil {
+		return nil, err
+	}
+
+	if !reflect.DeepEqual(renamed, expected) {
+		return nil, errors.New("renamed kubeconfig differs from expected one")
+	}
+
+	return result, nil
+}
+
+func renameOf(renames map[string]string, name string) string {
+	if renamed, ok := renames[name]; ok {
+		return renamed
+	}
+
+	return name
+}
+
+// RegexRenames builds renames for names matching pattern by replacing matches
+// with replacement, which may reference groups as $1 or ${name}
+func RegexRenames(names []string, pattern, replacement string) (map[string]string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, WithExitCode(ExitUsage, fmt.Errorf("invalid pattern %q: %w", pattern, err))
+	}
+
+	renames := map[string]string{}
+	for _, name := range names {
+		if !re.MatchString(name) {
+			continue
+		}
+
+		renamed := re.ReplaceAllString(name, replacement)
+		if renamed == "" {
+			return nil, WithExitCode(ExitUsage, fmt.Errorf("%q would be renamed to empty name", name))
+		}
+
+		if renamed != name {
+			renames[name] = renamed
+		}
+	}
+
+	if len(renames) == 0 {
+		return nil, WithExitCode(ExitNotFound, fmt.Errorf("nothing to rename matches %q", pattern))
+	}
+
+	return renames, nil
+}
+
+// ReadRenames reads yaml file mapping old names to new ones
+func ReadRenames(path string) (map[string]string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	renames := map[string]string{}
+	err = yaml.UnmarshalStrict(raw, &renames)
+	if err != nil {
+		return nil, WithExitCode(ExitValidation, fmt.Errorf("cannot parse %s: %w", path, err))
+	}
+
+	for old, renamed := range renames {
+		if renamed == "" {
+			return nil, WithExitCode(ExitValidation, fmt.Errorf("%s: new name of %q is empty", path, old))
+		}
+	}
+
+	return renames, nil
+}
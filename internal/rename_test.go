@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRename(t *testing.T) {
+	config := Kubeconfig{
+		Clusters: []ClusterEntry{{Name: "arn:aws:eks:eu-west-1:123:cluster/payments"}, {Name: "dev"}},
+		Contexts: []ContextEntry{
+			{Name: "arn:aws:eks:eu-west-1:123:cluster/payments", Context: Context{
+				Cluster: "arn:aws:eks:eu-west-1:123:cluster/payments", User: "arn:aws:eks:eu-west-1:123:cluster/payments",
+			}},
+			{Name: "dev", Context: Context{Cluster: "dev", User: "dev"}},
+		},
+		Users:          []UserEntry{{Name: "arn:aws:eks:eu-west-1:123:cluster/payments"}, {Name: "dev"}},
+		CurrentContext: "arn:aws:eks:eu-west-1:123:cluster/payments",
+	}
+
+	renames, err := RegexRenames(ContextNames(config), `^arn:.*/(.*)$`, "$1-prod")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"arn:aws:eks:eu-west-1:123:cluster/payments": "payments-prod"}, renames)
+
+	result, err := Rename(config, "context", renames)
+	require.NoError(t, err)
+	require.Equal(t, "payments-prod", result.Contexts[0].Name)
+	require.Equal(t, "payments-prod", result.CurrentContext)
+	require.Equal(t, config.Contexts[0].Context, result.Contexts[0].Context)
+
+	result, err = Rename(result, "cluster", renames)
+	require.NoError(t, err)
+	result, err = Rename(result, "user", renames)
+	require.NoError(t, err)
+	require.Equal(t, "payments-prod", result.Clusters[0].Name)
+	require.Equal(t, "payments-prod", result.Users[0].Name)
+	require.Equal(t, Context{Cluster: "payments-prod", User: "payments-prod"}, result.Contexts[0].Context)
+	require.Equal(t, "arn:aws:eks:eu-west-1:123:cluster/payments", config.Contexts[0].Name, "input must not be modified")
+
+	_, err = Rename(result, "user", map[string]string{"dev": "payments-prod"})
+	require.Equal(t, ExitConflict, ExitCode(err))
+
+	_, err = Rename(result, "user", map[string]string{"qa": "test"})
+	require.Equal(t, ExitNotFound, ExitCode(err))
+
+	swapped, err := Rename(result, "cluster", map[string]string{"dev": "payments-prod", "payments-prod": "dev"})
+	require.NoError(t, err)
+	require.Equal(t, Context{Cluster: "dev", User: "payments-prod"}, swapped.Contexts[0].Context)
+	require.Equal(t, Context{Cluster: "payments-prod", User: "dev"}, swapped.Contexts[1].Context)
+
+	_, err = RegexRenames(ContextNames(config), "^gke_", "")
+	require.Equal(t, ExitNotFound, ExitCode(err))
+}
+
+func TestRenameConf(t *testing.T) {
+	raw, err := RenameConf([]byte(unknownFieldsConf), "user", map[string]string{"oidc": "sso", "dev": "123"})
+	require.NoError(t, err)
+	requireUnknownFieldsKept(t, raw)
+
+	expected := strings.NewReplacer("user: oidc", "user: sso", "name: oidc\n  user:", "name: sso\n  user:",
+		"user: dev", "user: \"123\"", "name: dev\n  user:", "name: \"123\"\n  user:").Replace(unknownFieldsConf)
+	require.Equal(t, expected, string(raw))
+
+	raw, err = RenameConf([]byte(unknownFieldsConf), "context", map[string]string{"prod": "production"})
+	require.NoError(t, err)
+	require.Contains(t, string(raw), "- name: production\n  context:\n    cluster: prod\n")
+	require.Contains(t, string(raw), "current-context: production\n")
+
+	_, err = RenameConf([]byte(unknownFieldsConf), "cluster", map[string]string{"prod": "dev"})
+	require.Equal(t, ExitConflict, ExitCode(err))
+}
+
+func TestReadRenames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "renames.yaml")
+
+	require.NoError(t, os.WriteFile(path, []byte("old: new\n\"arn:aws:eks:eu-west-1:123:cluster/a\": a\n"), 0600))
+	renames, err := ReadRenames(path)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"old": "new", "arn:aws:eks:eu-west-1:123:cluster/a": "a"}, renames)
+
+	require.NoError(t, os.WriteFile(path, []byte("- old\n"), 0600))
+	_, err = ReadRenames(path)
+	require.Equal(t, ExitValidation, ExitCode(err))
+}
@@ -0,0 +1,117 @@
+package internal
+
+import (
+	"path/filepath"
+)
+
+// SARIF types describe minimal subset of SARIF 2.1.0 log format
+// understood by code scanning tools
+
+// SARIFLog is root object of SARIF report
+type SARIFLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []SARIFRun `json:"runs"`
+}
+
+// SARIFRun represents single run of analysis tool
+type SARIFRun struct {
+	Tool    SARIFTool     `json:"tool"`
+	Results []SARIFResult `json:"results"`
+}
+
+// SARIFTool describes analysis tool
+type SARIFTool struct {
+	Driver SARIFDriver `json:"driver"`
+}
+
+// SARIFDriver describes analysis tool and rules it checks
+type SARIFDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []SARIFRule `json:"rules"`
+}
+
+// SARIFRule describes single rule of analysis tool
+type SARIFRule struct {
+	ID                   string             `json:"id"`
+	ShortDescription     SARIFMessage       `json:"shortDescription"`
+	DefaultConfiguration SARIFConfiguration `json:"defaultConfiguration"`
+}
+
+// SARIFConfiguration describes default level of rule
+type SARIFConfiguration struct {
+	Level string `json:"level"`
+}
+
+// SARIFMessage is text message of SARIF report
+type SARIFMessage struct {
+	Text string `json:"text"`
+}
+
+// SARIFResult represents single finding
+type SARIFResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   SARIFMessage    `json:"message"`
+	Locations []SARIFLocation `json:"locations"`
+}
+
+// SARIFLocation represents location of finding
+type SARIFLocation struct {
+	PhysicalLocation SARIFPhysicalLocation `json:"physicalLocation"`
+}
+
+// SARIFPhysicalLocation represents file where finding is located
+type SARIFPhysicalLocation struct {
+	ArtifactLocation SARIFArtifactLocation `json:"artifactLocation"`
+}
+
+// SARIFArtifactLocation represents uri of file where finding is located
+type SARIFArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// NewSARIFLog builds SARIF report of audit findings for kubeconfig at path
+func NewSARIFLog(findings []Finding, path string) SARIFLog {
+	rules := []SARIFRule{}
+	for _, rule := range AuditRules {
+		rules = append(rules, SARIFRule{
+			ID:                   rule.ID,
+			ShortDescription:     SARIFMessage{Text: rule.Description},
+			DefaultConfiguration: SARIFConfiguration{Level: string(rule.Severity)},
+		})
+	}
+
+	results := []SARIFResult{}
+	for _, finding := range findings {
+		location := finding.Section
+		if finding.Name != "" {
+			location += " " + finding.Name
+		}
+
+		results = append(results, SARIFResult{
+			RuleID:  finding.Rule,
+			Level:   string(finding.Severity),
+			Message: SARIFMessage{Text: location + ": " + finding.Message},
+			Locations: []SARIFLocation{{
+				PhysicalLocation: SARIFPhysicalLocation{
+					ArtifactLocation: SARIFArtifactLocation{URI: filepath.ToSlash(path)},
+				},
+			}},
+		})
+	}
+
+	return SARIFLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []SARIFRun{{
+			Tool: SARIFTool{Driver: SARIFDriver{
+				Name:           "konfig",
+				InformationURI: "https://github.com/ansavin/konfig",
+				Rules:          rules,
+			}},
+			Results: results,
+		}},
+	}
+}
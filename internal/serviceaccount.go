@@ -0,0 +1,125 @@
+package internal
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ServiceAccountTokenType is type of Secret holding service account token
+const ServiceAccountTokenType = "kubernetes.io/service-account-token"
+
+// ServiceAccountNameAnnotation is annotation of token Secret naming its service account
+const ServiceAccountNameAnnotation = "kubernetes.io/service-account.name"
+
+// Secret represents fields of k8s Secret manifest relevant to konfig
+type Secret struct {
+	Kind     string `yaml:"kind"`
+	Type     string `yaml:"type"`
+	Metadata struct {
+		Name        string            `yaml:"name"`
+		Namespace   string            `yaml:"namespace"`
+		Annotations map[string]string `yaml:"annotations"`
+	} `yaml:"metadata"`
+	Data       map[string]string `yaml:"data"`
+	StringData map[string]string `yaml:"stringData"`
+}
+
+// ServiceAccountCredentials is what is needed to access API as service account
+type ServiceAccountCredentials struct {
+	Name      string
+	Namespace string
+	Token     string
+	CA        []byte
+}
+
+// ParseServiceAccountSecret reads service account credentials from manifest of
+// token Secret, as printed by 'kubectl get secret -o yaml'
+func ParseServiceAccountSecret(raw []byte) (ServiceAccountCredentials, error) {
+	secret := Secret{}
+
+	err := yaml.Unmarshal(raw, &secret)
+	if err != nil {
+		return ServiceAccountCredentials{}, WithExitCode(ExitValidation, fmt.Errorf("cannot read secret: %w", err))
+	}
+
+	if secret.Kind != "Secret" || secret.Type != ServiceAccountTokenType {
+		return ServiceAccountCredentials{}, WithExitCode(ExitValidation, fmt.Errorf(
+			"manifest is not a Secret of type %s", ServiceAccountTokenType,
+		))
+	}
+
+	value := func(key string) (string, error) {
+		if value, ok := secret.StringData[key]; ok {
+			return value, nil
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(secret.Data[key])
+		if err != nil {
+			return "", WithExitCode(ExitValidation, fmt.Errorf("invalid base64 in %s: %w", key, err))
+		}
+
+		return string(decoded), nil
+	}
+
+	creds := ServiceAccountCredentials{
+		Name:      secret.Metadata.Annotations[ServiceAccountNameAnnotation],
+		Namespace: secret.Metadata.Namespace,
+	}
+
+	if creds.Name == "" {
+		creds.Name = secret.Metadata.Name
+	}
+
+	creds.Token, err = value("token")
+	if err != nil {
+		return ServiceAccountCredentials{}, err
+	}
+
+	if creds.Token == "" {
+		return ServiceAccountCredentials{}, WithExitCode(ExitValidation, errors.New(
+			"secret has no token, it may not be populated by token controller yet",
+		))
+	}
+
+	ca, err := value("ca.crt")
+	if err != nil {
+		return ServiceAccountCredentials{}, err
+	}
+	creds.CA = []byte(ca)
+
+	namespace, err := value("namespace")
+	if err != nil {
+		return ServiceAccountCredentials{}, err
+	}
+	if namespace != "" {
+		creds.Namespace = namespace
+	}
+
+	return creds, nil
+}
+
+// SingleContextConf returns kubeconfig with cluster, user and context all named
+// name, with current-context set to it
+func SingleContextConf(name string, cluster Cluster, user User, namespace string) Kubeconfig {
+	return Kubeconfig{
+		APIVersion:     "v1",
+		Kind:           "Config",
+		Clusters:       []ClusterEntry{{Name: name, Cluster: cluster}},
+		Contexts:       []ContextEntry{{Name: name, Context: Context{Cluster: name, User: name, Namespace: namespace}}},
+		CurrentContext: name,
+		Users:          []UserEntry{{Name: name, User: user}},
+	}
+}
+
+// ServiceAccountConf returns kubeconfig using service account credentials to access server
+func ServiceAccountConf(creds ServiceAccountCredentials, name, server string) Kubeconfig {
+	cluster := Cluster{Server: server}
+	if len(creds.CA) > 0 {
+		cluster.CertificateAuthorityData = base64.StdEncoding.EncodeToString(creds.CA)
+	}
+
+	return SingleContextConf(name, cluster, User{Token: creds.Token}, creds.Namespace)
+}
@@ -0,0 +1,105 @@
+package internal
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseServiceAccountSecret(t *testing.T) {
+	encode := func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	}
+
+	raw := []byte(`apiVersion: v1
+kind: Secret
+type: kubernetes.io/service-account-token
+metadata:
+  name: ci-token-x7k2p
+  namespace: ci
+  annotations:
+    kubernetes.io/service-account.name: ci
+data:
+  token: ` + encode("eyJhbGciOi") + `
+  ca.crt: ` + encode("-----BEGIN CERTIFICATE-----") + `
+  namespace: ` + encode("ci") + `
+`)
+
+	creds, err := ParseServiceAccountSecret(raw)
+	require.NoError(t, err)
+	require.Equal(t, ServiceAccountCredentials{
+		Name: "ci", Namespace: "ci", Token: "eyJhbGciOi", CA: []byte("-----BEGIN CERTIFICATE-----"),
+	}, creds)
+
+	config := ServiceAccountConf(creds, "ci", "https://prod:6443")
+	require.Equal(t, Kubeconfig{
+		APIVersion: "v1",
+		Kind:       "Config",
+		Clusters: []ClusterEntry{{Name: "ci", Cluster: Cluster{
+			Server: "https://prod:6443", CertificateAuthorityData: encode("-----BEGIN CERTIFICATE-----"),
+		}}},
+		Contexts:       []ContextEntry{{Name: "ci", Context: Context{Cluster: "ci", User: "ci", Namespace: "ci"}}},
+		CurrentContext: "ci",
+		Users:          []UserEntry{{Name: "ci", User: User{Token: "eyJhbGciOi"}}},
+	}, config)
+
+	_, err = ParseServiceAccountSecret([]byte("kind: Secret\ntype: Opaque\n"))
+	require.Equal(t, ExitValidation, ExitCode(err))
+
+	_, err = ParseServiceAccountSecret([]byte("kind: Secret\ntype: kubernetes.io/service-account-token\n"))
+	require.Equal(t, ExitValidation, ExitCode(err))
+}
+
+func TestMergeEntries(t *testing.T) {
+	config := Kubeconfig{
+		Clusters: []ClusterEntry{{Name: "prod", Cluster: Cluster{Server: "https://prod"}}},
+		Contexts: []ContextEntry{{Name: "prod", Context: Context{Cluster: "prod", User: "prod"}}},
+		Users:    []UserEntry{{Name: "prod", User: User{Token: "old"}}},
+	}
+	extra := SingleContextConf("prod", Cluster{Server: "https://prod"}, User{Token: "new"}, "")
+
+	_, err := MergeEntries(config, extra, false)
+	require.Equal(t, ExitConflict, ExitCode(err))
+
+	result, err := MergeEntries(config, extra, true)
+	require.NoError(t, err)
+	require.Equal(t, extra.Users, result.Users)
+	require.Equal(t, "old", config.Users[0].User.Token, "input must not be modified")
+
+	result, err = MergeEntries(config, SingleContextConf("dev", Cluster{Server: "https://dev"}, User{}, ""), false)
+	require.NoError(t, err)
+	require.Equal(t, []string{"prod", "dev"}, ContextNames(result))
+}
+
+func TestMergeEntriesKeepsUnknownFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kube", "config")
+	extra := ServiceAccountConf(ServiceAccountCredentials{Name: "ci", Namespace: "default", Token: "abc"}, "ci", "https://ci")
+
+	// import-sa and add merge generated entries this way
+	merge := func(current Kubeconfig) (Kubeconfig, error) {
+		return MergeEntries(current, extra, false)
+	}
+
+	require.NoError(t, UpdateOrCreateConf(path, time.Second, merge))
+
+	config, err := ReadConf(path)
+	require.NoError(t, err)
+	require.Equal(t, "v1", config.APIVersion)
+	require.Equal(t, []string{"ci"}, ContextNames(config))
+
+	require.NoError(t, os.WriteFile(path, []byte(unknownFieldsConf), 0600))
+	require.NoError(t, UpdateOrCreateConf(path, time.Second, merge))
+
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	requireUnknownFieldsKept(t, raw)
+
+	config, err = ReadConf(path)
+	require.NoError(t, err)
+	require.Equal(t, []string{"prod", "dev", "ci"}, ContextNames(config))
+	require.Equal(t, extra.Users[0], config.Users[2])
+}
@@ -0,0 +1,422 @@
+package internal
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// namedSection describes list of named entries of kubeconfig, like clusters
+type namedSection struct {
+	key    string
+	schema reflect.Type
+}
+
+// namedSections maps top-level keys of named lists to keys and types of their entries
+var namedSections = map[string]namedSection{
+	"clusters": {"cluster", reflect.TypeOf(Cluster{})},
+	"contexts": {"context", reflect.TypeOf(Context{})},
+	"users":    {"user", reflect.TypeOf(User{})},
+}
+
+// fieldPath is parsed path of kubeconfig field like clusters.<name>.server
+type fieldPath struct {
+	path string
+	// section is top-level key
+	section string
+	// name is name of entry of named list, empty for other top-level keys
+	name string
+	// fields are keys inside entry
+	fields []string
+	// schema is type of addressed value
+	schema reflect.Type
+}
+
+// SetField sets field of kubeconfig addressed by path like clusters.<name>.server,
+// users.<name>.exec.args or current-context to value. Clusters, contexts and users
+// which do not exist are created. Value of string and bool fields is taken as is,
+// lists and objects are parsed from yaml. Comments, order of keys and quoting
+// in raw kubeconfig are preserved
+func SetField(raw []byte, path, value string) ([]byte, error) {
+	return editDocument(raw, path, func(doc *yamlv3.Node, fp fieldPath) error {
+		if fp.name != "" && len(fp.fields) == 0 {
+			return WithExitCode(ExitUsage, fmt.Errorf("%s addresses whole entry, specify its field", path))
+		}
+
+		node, err := valueNode(fp, value)
+		if err != nil {
+			return err
+		}
+
+		parent := doc
+		keys := fp.fields
+
+		if fp.name == "" {
+			keys = []string{fp.section}
+		} else {
+			list := mappingValue(doc, fp.section, yamlv3.SequenceNode)
+			parent = findEntry(list, fp.name)
+
+			if parent == nil {
+				parent = newMapping()
+				setMappingValue(parent, "name", stringNode(fp.name))
+				setMappingValue(parent, namedSections[fp.section].key, newMapping())
+				appendToCollection(list, parent)
+			}
+
+			parent = mappingValue(parent, namedSections[fp.section].key, yamlv3.MappingNode)
+		}
+
+		for _, key := range keys[:len(keys)-1] {
+			parent = mappingValue(parent, key, yamlv3.MappingNode)
+		}
+
+		setMappingValue(parent, keys[len(keys)-1], node)
+		return nil
+	})
+}
+
+// UnsetField removes field of kubeconfig addressed by path. Path of entry itself,
+// like contexts.<name>, removes the whole entry
+func UnsetField(raw []byte, path string) ([]byte, error) {
+	return editDocument(raw, path, func(doc *yamlv3.Node, fp fieldPath) error {
+		notFound := WithExitCode(ExitNotFound, fmt.Errorf("%s is not set", path))
+
+		if fp.name == "" {
+			if !removeMappingKey(doc, fp.section) {
+				return notFound
+			}
+			return nil
+		}
+
+		list := lookupMapping(doc, fp.section)
+		entry := findEntry(list, fp.name)
+		if entry == nil {
+			return notFound
+		}
+
+		if len(fp.fields) == 0 {
+			for i, item := range list.Content {
+				if item == entry {
+					list.Content = append(list.Content[:i], list.Content[i+1:]...)
+					return nil
+				}
+			}
+		}
+
+		parent := lookupMapping(entry, namedSections[fp.section].key)
+		for _, key := range fp.fields[:len(fp.fields)-1] {
+			parent = lookupMapping(parent, key)
+		}
+
+		if !removeMappingKey(parent, fp.fields[len(fp.fields)-1]) {
+			return notFound
+		}
+
+		return nil
+	})
+}
+
+// ReadValue returns value to set to field: arg itself, content of file if arg
+// is @<file>, or stdin if arg is -. Trailing newline of content is dropped and
+// content for *-data fields is base64 encoded, so certificates can be set from files
+func ReadValue(arg, path string, stdin io.Reader) (string, error) {
+	var content []byte
+	var err error
+
+	switch {
+	case arg == "-":
+		content, err = io.ReadAll(stdin)
+	case strings.HasPrefix(arg, "@"):
+		content, err = os.ReadFile(arg[1:])
+	default:
+		return arg, nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	if strings.HasSuffix(path, "-data") {
+		return base64.StdEncoding.EncodeToString(content), nil
+	}
+
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// editDocument parses raw kubeconfig and path, applies edit and checks result
+// still is a kubeconfig
+func editDocument(raw []byte, path string, edit func(*yamlv3.Node, fieldPath) error) ([]byte, error) {
+	d, err := parseDocument(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	fp, err := parseFieldPath(d.root, path)
+	if err != nil {
+		return nil, err
+	}
+
+	err = edit(d.root, fp)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := d.encode()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = ParseConf(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// parseFieldPath splits path into parts. Entry names may contain dots, so the longest
+// existing entry name path continues with is taken
+func parseFieldPath(root *yamlv3.Node, path string) (fieldPath, error) {
+	fp := fieldPath{path: path}
+	invalid := func(format string, args ...interface{}) (fieldPath, error) {
+		return fieldPath{}, WithExitCode(ExitUsage, fmt.Errorf("invalid path %s: "+format, append([]interface{}{path}, args...)...))
+	}
+
+	parts := strings.SplitN(path, ".", 2)
+	fp.section = parts[0]
+
+	section, named := namedSections[fp.section]
+	if !named {
+		if len(parts) > 1 {
+			return invalid("%s has no fields", fp.section)
+		}
+
+		field, ok := fieldByTag(reflect.TypeOf(Kubeconfig{}), fp.section)
+		if !ok || field.Kind() != reflect.String {
+			return invalid("unknown field %s", fp.section)
+		}
+
+		fp.schema = field
+		return fp, nil
+	}
+
+	if len(parts) == 1 || parts[1] == "" {
+		return invalid("name of %s entry is missing", fp.section)
+	}
+
+	rest := parts[1]
+	for _, name := range entryNames(lookupMapping(root, fp.section)) {
+		if (rest == name || strings.HasPrefix(rest, name+".")) && len(name) > len(fp.name) {
+			fp.name = name
+		}
+	}
+
+	if fp.name == "" {
+		fp.name = strings.SplitN(rest, ".", 2)[0]
+	}
+
+	if rest != fp.name {
+		fp.fields = strings.Split(rest[len(fp.name)+1:], ".")
+	}
+
+	fp.schema = section.schema
+	for _, key := range fp.fields {
+		switch fp.schema.Kind() {
+		case reflect.Struct:
+			field, ok := fieldByTag(fp.schema, key)
+			if !ok {
+				return invalid("unknown field %s", key)
+			}
+
+			fp.schema = field
+		case reflect.Map:
+			// keys of maps like auth-provider.config are arbitrary
+			fp.schema = fp.schema.Elem()
+		default:
+			return invalid("%s has no fields", key)
+		}
+	}
+
+	return fp, nil
+}
+
+// fieldByTag returns type of field of struct t with given yaml name
+func fieldByTag(t reflect.Type, name string) (reflect.Type, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("yaml"), ",")[0]
+
+		if tag == name && tag != "-" {
+			if field.Type.Kind() == reflect.Ptr {
+				return field.Type.Elem(), true
+			}
+			return field.Type, true
+		}
+	}
+
+	return nil, false
+}
+
+// valueNode builds yaml node for value of field addressed by fp
+func valueNode(fp fieldPath, value string) (*yamlv3.Node, error) {
+	switch fp.schema.Kind() {
+	case reflect.String:
+		return stringNode(value), nil
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, WithExitCode(ExitUsage, fmt.Errorf("%s must be true or false", fp.path))
+		}
+
+		return &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(parsed)}, nil
+	}
+
+	var doc yamlv3.Node
+	err := yamlv3.Unmarshal([]byte(value), &doc)
+	if err == nil && len(doc.Content) == 0 {
+		err = errors.New("value is empty")
+	}
+	if err == nil {
+		err = doc.Content[0].Decode(reflect.New(fp.schema).Interface())
+	}
+	if err != nil {
+		return nil, WithExitCode(ExitUsage, fmt.Errorf("invalid value of %s: %w", fp.path, err))
+	}
+
+	blockStyle(doc.Content[0])
+	return doc.Content[0], nil
+}
+
+// blockStyle switches collections parsed from flow yaml like [a, b] to block style
+func blockStyle(node *yamlv3.Node) {
+	node.Style &^= yamlv3.FlowStyle
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+func stringNode(value string) *yamlv3.Node {
+	return &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: value}
+}
+
+func newMapping() *yamlv3.Node {
+	return &yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map"}
+}
+
+// lookupMapping returns value of key of mapping node or nil
+func lookupMapping(mapping *yamlv3.Node, key string) *yamlv3.Node {
+	if mapping == nil || mapping.Kind != yamlv3.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// mappingValue returns value of key of mapping node, creating empty collection
+// of given kind if key is missing or null
+func mappingValue(mapping *yamlv3.Node, key string, kind yamlv3.Kind) *yamlv3.Node {
+	value := lookupMapping(mapping, key)
+	if value != nil && value.Kind == kind {
+		return value
+	}
+
+	value = newMapping()
+	if kind == yamlv3.SequenceNode {
+		value = &yamlv3.Node{Kind: yamlv3.SequenceNode, Tag: "!!seq"}
+	}
+
+	setMappingValue(mapping, key, value)
+	return value
+}
+
+// setMappingValue sets value of key of mapping node keeping comments and quoting of old value
+func setMappingValue(mapping *yamlv3.Node, key string, value *yamlv3.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != key {
+			continue
+		}
+
+		old := mapping.Content[i+1]
+		value.HeadComment, value.LineComment, value.FootComment = old.HeadComment, old.LineComment, old.FootComment
+
+		if old.Kind == yamlv3.ScalarNode && value.Kind == yamlv3.ScalarNode && value.Tag == "!!str" {
+			value.Style = old.Style &^ yamlv3.TaggedStyle
+		}
+
+		mapping.Content[i+1] = value
+		return
+	}
+
+	appendToCollection(mapping, stringNode(key), value)
+}
+
+// appendToCollection appends nodes to mapping or sequence, switching empty
+// flow collections like [] to block style
+func appendToCollection(collection *yamlv3.Node, nodes ...*yamlv3.Node) {
+	if len(collection.Content) == 0 {
+		collection.Style &^= yamlv3.FlowStyle
+	}
+
+	collection.Content = append(collection.Content, nodes...)
+}
+
+// removeMappingKey removes key from mapping node and reports whether it was there
+func removeMappingKey(mapping *yamlv3.Node, key string) bool {
+	if mapping == nil || mapping.Kind != yamlv3.MappingNode {
+		return false
+	}
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// findEntry returns entry of named list with given name or nil
+func findEntry(list *yamlv3.Node, name string) *yamlv3.Node {
+	if list == nil || list.Kind != yamlv3.SequenceNode {
+		return nil
+	}
+
+	for _, item := range list.Content {
+		if nameNode := lookupMapping(item, "name"); nameNode != nil && nameNode.Value == name {
+			return item
+		}
+	}
+
+	return nil
+}
+
+// entryNames returns names of entries of named list
+func entryNames(list *yamlv3.Node) []string {
+	names := []string{}
+	if list == nil || list.Kind != yamlv3.SequenceNode {
+		return names
+	}
+
+	for _, item := range list.Content {
+		if nameNode := lookupMapping(item, "name"); nameNode != nil {
+			names = append(names, nameNode.Value)
+		}
+	}
+
+	return names
+}
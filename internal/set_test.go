@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const editableConf = `# managed by hand
+apiVersion: v1
+kind: Config
+clusters:
+- name: prod # primary
+  cluster:
+    server: "https://prod:6443"
+    certificate-authority-data: QUJD
+- name: gke.example.com
+  cluster:
+    server: https://gke
+contexts:
+- name: prod
+  context:
+    cluster: prod
+    user: admin
+current-context: prod
+users:
+- name: admin
+  user:
+    token: secret
+`
+
+func TestSetField(t *testing.T) {
+	raw, err := SetField([]byte(editableConf), "clusters.prod.server", "https://prod.example.com:6443")
+	require.NoError(t, err)
+	require.Equal(t, strings.Replace(editableConf, "https://prod:6443", "https://prod.example.com:6443", 1), string(raw))
+
+	raw, err = SetField(raw, "clusters.gke.example.com.insecure-skip-tls-verify", "true")
+	require.NoError(t, err)
+	require.Contains(t, string(raw), "    server: https://gke\n    insecure-skip-tls-verify: true\n")
+
+	raw, err = SetField(raw, "contexts.dev.namespace", "0123")
+	require.NoError(t, err)
+	require.Contains(t, string(raw), "- name: dev\n  context:\n    namespace: \"0123\"\n")
+
+	raw, err = SetField(raw, "users.admin.exec.args", "[eks, get-token]")
+	require.NoError(t, err)
+	require.Contains(t, string(raw), "    exec:\n      args:\n      - eks\n      - get-token\n")
+
+	config, err := ParseConf(raw)
+	require.NoError(t, err)
+	require.Equal(t, "https://prod.example.com:6443", config.Clusters[0].Cluster.Server)
+	require.True(t, config.Clusters[1].Cluster.InsecureSkipTLSVerify)
+	require.Equal(t, ContextEntry{Name: "dev", Context: Context{Namespace: "0123"}}, config.Contexts[1])
+	require.Equal(t, []string{"eks", "get-token"}, config.Users[0].User.Exec.Args)
+	require.True(t, strings.HasPrefix(string(raw), "# managed by hand\n"))
+
+	raw, err = SetField(raw, "clusters.prod.proxy-url", "http://proxy:3128")
+	require.NoError(t, err)
+	require.Contains(t, string(raw), "    proxy-url: http://proxy:3128\n")
+
+	raw, err = SetField(raw, "users.admin.auth-provider.config.client-id", "konfig")
+	require.NoError(t, err)
+	require.Contains(t, string(raw), "    auth-provider:\n      config:\n        client-id: konfig\n")
+
+	_, err = SetField(raw, "clusters.prod.servers", "https://prod")
+	require.Equal(t, ExitUsage, ExitCode(err))
+
+	_, err = SetField(raw, "clusters.prod.insecure-skip-tls-verify", "maybe")
+	require.Equal(t, ExitUsage, ExitCode(err))
+
+	_, err = SetField(raw, "users.admin.exec.args", "{a: b}")
+	require.Equal(t, ExitUsage, ExitCode(err))
+
+	_, err = SetField(raw, "users.admin", "x")
+	require.Equal(t, ExitUsage, ExitCode(err))
+
+	raw, err = SetField(nil, "current-context", "prod")
+	require.NoError(t, err)
+	require.Equal(t, "apiVersion: v1\nkind: Config\ncurrent-context: prod\n", string(raw))
+}
+
+func TestUnsetField(t *testing.T) {
+	raw, err := UnsetField([]byte(editableConf), "clusters.prod.certificate-authority-data")
+	require.NoError(t, err)
+	require.Equal(t, strings.Replace(editableConf, "    certificate-authority-data: QUJD\n", "", 1), string(raw))
+
+	raw, err = UnsetField(raw, "clusters.gke.example.com")
+	require.NoError(t, err)
+	require.NotContains(t, string(raw), "gke")
+
+	raw, err = UnsetField(raw, "current-context")
+	require.NoError(t, err)
+	require.NotContains(t, string(raw), "current-context")
+
+	_, err = UnsetField(raw, "contexts.prod.namespace")
+	require.Equal(t, ExitNotFound, ExitCode(err))
+
+	_, err = UnsetField(raw, "users.nobody")
+	require.Equal(t, ExitNotFound, ExitCode(err))
+}
+
+func TestReadValue(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "token")
+	require.NoError(t, os.WriteFile(file, []byte("abc\n"), 0600))
+
+	value, err := ReadValue("@"+file, "users.admin.token", nil)
+	require.NoError(t, err)
+	require.Equal(t, "abc", value)
+
+	value, err = ReadValue("@"+file, "clusters.prod.certificate-authority-data", nil)
+	require.NoError(t, err)
+	require.Equal(t, base64.StdEncoding.EncodeToString([]byte("abc\n")), value)
+
+	value, err = ReadValue("-", "users.admin.token", strings.NewReader("xyz\n"))
+	require.NoError(t, err)
+	require.Equal(t, "xyz", value)
+
+	value, err = ReadValue("plain", "users.admin.token", nil)
+	require.NoError(t, err)
+	require.Equal(t, "plain", value)
+}
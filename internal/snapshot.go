@@ -0,0 +1,322 @@
+package internal
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultSnapshotsFolder is folder inside backup folder where snapshot contents are stored
+const DefaultSnapshotsFolder = "snapshots"
+
+// DefaultSnapshotIndexFile is file inside backup folder which lists taken snapshots
+const DefaultSnapshotIndexFile = "index"
+
+// ShortIDLength is length of snapshot id prefix shown to users
+const ShortIDLength = 12
+
+// Snapshot represents single backup of kubeconfig in snapshot store
+type Snapshot struct {
+	ID      string
+	Created time.Time
+	Source  string
+}
+
+// ShortID returns abbreviated snapshot id
+func (s Snapshot) ShortID() string {
+	if len(s.ID) < ShortIDLength {
+		return s.ID
+	}
+
+	return s.ID[:ShortIDLength]
+}
+
+// SnapshotStore is content-addressed storage of kubeconfig backups: every distinct
+// kubeconfig state is stored once under hash of its canonical form, while index
+// file keeps history of when and from where snapshots were taken
+type SnapshotStore struct {
+	Dir string
+}
+
+// DefaultSnapshotStore returns snapshot store located in $HOME/.konfig
+func DefaultSnapshotStore() SnapshotStore {
+	return SnapshotStore{Dir: filepath.Join(os.Getenv("HOME"), DefaultBackupFolder)}
+}
+
+// CanonicalHash returns hash of kubeconfig which does not depend on formatting,
+// order of keys or order of named clusters, contexts and users
+func CanonicalHash(raw []byte) (string, error) {
+	var doc interface{}
+
+	err := yaml.Unmarshal(raw, &doc)
+	if err != nil {
+		return "", fmt.Errorf("cannot read kubeconfig: %w", err)
+	}
+
+	if m, ok := doc.(map[interface{}]interface{}); ok {
+		for _, section := range []string{"clusters", "contexts", "users"} {
+			if list, ok := m[section].([]interface{}); ok {
+				sort.SliceStable(list, func(i, j int) bool {
+					return entryName(list[i]) < entryName(list[j])
+				})
+			}
+		}
+	}
+
+	canonical, err := yaml.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func entryName(entry interface{}) string {
+	m, ok := entry.(map[interface{}]interface{})
+	if !ok {
+		return ""
+	}
+
+	return fmt.Sprintf("%v", m["name"])
+}
+
+// Save stores kubeconfig content taken from source. If ifChanged is set and content
+// is identical to the latest snapshot of the same source, nothing is stored and false is returned
+func (s SnapshotStore) Save(raw []byte, source string, ifChanged bool) (Snapshot, bool, error) {
+	id, err := CanonicalHash(raw)
+	if err != nil {
+		return Snapshot{}, false, err
+	}
+
+	if ifChanged {
+		latest, err := s.LatestFrom(source)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return Snapshot{}, false, err
+		}
+
+		if err == nil && latest.ID == id {
+			return latest, false, nil
+		}
+	}
+
+	err = os.MkdirAll(filepath.Join(s.Dir, DefaultSnapshotsFolder), os.FileMode(0700))
+	if err != nil {
+		return Snapshot{}, false, err
+	}
+
+	object := s.objectPath(id)
+	_, err = os.Stat(object)
+	if errors.Is(err, os.ErrNotExist) {
+		err = WriteFileAtomic(object, raw, os.FileMode(0600))
+	}
+	if err != nil {
+		return Snapshot{}, false, err
+	}
+
+	snapshot := Snapshot{ID: id, Created: time.Now().UTC(), Source: source}
+
+	// backups may run concurrently from cron or shell hooks, index is locked
+	// so none of them loses entry added by another
+	err = WithLock(s.indexPath(), DefaultLockTimeout, func() error {
+		index, err := os.ReadFile(s.indexPath())
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		index = append(index, fmt.Sprintf("%s %s %s\n", snapshot.Created.Format(time.RFC3339), snapshot.ID, snapshot.Source)...)
+
+		return WriteFileAtomic(s.indexPath(), index, os.FileMode(0600))
+	})
+	if err != nil {
+		return Snapshot{}, false, err
+	}
+
+	return snapshot, true, nil
+}
+
+// List returns all taken snapshots, newest first
+func (s SnapshotStore) List() ([]Snapshot, error) {
+	in, err := os.Open(s.indexPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	snapshots := []Snapshot{}
+	scanner := bufio.NewScanner(in)
+
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), " ", 3)
+		if len(fields) < 2 {
+			continue
+		}
+
+		created, err := time.Parse(time.RFC3339, fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("corrupted snapshot index: %w", err)
+		}
+
+		snapshot := Snapshot{ID: fields[1], Created: created}
+		if len(fields) == 3 {
+			snapshot.Source = fields[2]
+		}
+
+		snapshots = append(snapshots, snapshot)
+	}
+
+	for i, j := 0, len(snapshots)-1; i < j; i, j = i+1, j-1 {
+		snapshots[i], snapshots[j] = snapshots[j], snapshots[i]
+	}
+
+	return snapshots, scanner.Err()
+}
+
+// Latest returns the most recent snapshot or os.ErrNotExist if there are none
+func (s SnapshotStore) Latest() (Snapshot, error) {
+	snapshots, err := s.List()
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	if len(snapshots) == 0 {
+		return Snapshot{}, fmt.Errorf("no snapshots found in %s: %w", s.Dir, os.ErrNotExist)
+	}
+
+	return snapshots[0], nil
+}
+
+// LatestFrom returns the most recent snapshot taken from source or os.ErrNotExist if there are none
+func (s SnapshotStore) LatestFrom(source string) (Snapshot, error) {
+	snapshots, err := s.List()
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	for _, snapshot := range snapshots {
+		if snapshot.Source == source {
+			return snapshot, nil
+		}
+	}
+
+	return Snapshot{}, fmt.Errorf("no snapshots of %s found in %s: %w", source, s.Dir, os.ErrNotExist)
+}
+
+// Find returns the most recent snapshot which id starts with given prefix
+func (s SnapshotStore) Find(prefix string) (Snapshot, error) {
+	snapshots, err := s.List()
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	var found *Snapshot
+	for i := range snapshots {
+		if !strings.HasPrefix(snapshots[i].ID, prefix) {
+			continue
+		}
+
+		if found != nil && found.ID != snapshots[i].ID {
+			return Snapshot{}, fmt.Errorf("snapshot id %s is ambiguous", prefix)
+		}
+
+		if found == nil {
+			found = &snapshots[i]
+		}
+	}
+
+	if found == nil {
+		return Snapshot{}, fmt.Errorf("snapshot %s: %w", prefix, os.ErrNotExist)
+	}
+
+	return *found, nil
+}
+
+// Read returns kubeconfig content stored in snapshot
+func (s SnapshotStore) Read(snapshot Snapshot) ([]byte, error) {
+	return os.ReadFile(s.objectPath(snapshot.ID))
+}
+
+// Restore writes kubeconfig stored in snapshot to path. If snapshot was taken from
+// kubeconfig in another folder, relative file references are rewritten to stay valid
+func (s SnapshotStore) Restore(snapshot Snapshot, path string) error {
+	raw, err := s.Read(snapshot)
+	if err != nil {
+		return err
+	}
+
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+
+	if snapshot.Source == "" || filepath.Dir(snapshot.Source) == dir {
+		return WriteFileAtomic(path, raw, os.FileMode(0600))
+	}
+
+	config, err := ParseConf(raw)
+	if err != nil {
+		return err
+	}
+
+	config.Dir = filepath.Dir(snapshot.Source)
+	rebased, warnings := RebasePaths(config, dir)
+	Warn(warnings...)
+
+	raw, err = PatchConf(raw, config, rebased)
+	if err != nil {
+		return err
+	}
+
+	return WriteFileAtomic(path, raw, os.FileMode(0600))
+}
+
+func (s SnapshotStore) objectPath(id string) string {
+	return filepath.Join(s.Dir, DefaultSnapshotsFolder, id)
+}
+
+func (s SnapshotStore) indexPath() string {
+	return filepath.Join(s.Dir, DefaultSnapshotIndexFile)
+}
+
+// ReadBackupConf reads kubeconfig from backup, which is either a custom backup
+// file or id of snapshot in default snapshot store
+func ReadBackupConf(backup string) (Kubeconfig, error) {
+	if _, err := os.Stat(backup); err == nil {
+		return ReadConf(backup)
+	}
+
+	store := DefaultSnapshotStore()
+
+	snapshot, err := store.Find(backup)
+	if err != nil {
+		return Kubeconfig{}, err
+	}
+
+	raw, err := store.Read(snapshot)
+	if err != nil {
+		return Kubeconfig{}, err
+	}
+
+	config, err := ParseConf(raw)
+	if err != nil {
+		return Kubeconfig{}, err
+	}
+
+	if snapshot.Source != "" {
+		config.Dir = filepath.Dir(snapshot.Source)
+	}
+
+	return config, nil
+}
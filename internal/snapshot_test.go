@@ -0,0 +1,189 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalHash(t *testing.T) {
+	a := `
+apiVersion: v1
+kind: Config
+clusters:
+- name: a
+  cluster:
+    server: https://a
+- name: b
+  cluster:
+    server: https://b
+`
+	b := `
+kind: Config
+clusters:
+- cluster: {server: "https://b"}
+  name: b
+- cluster: {server: "https://a"}
+  name: a
+apiVersion: v1
+`
+	c := `
+apiVersion: v1
+kind: Config
+clusters:
+- name: a
+  cluster:
+    server: https://c
+`
+
+	hashA, err := CanonicalHash([]byte(a))
+	require.NoError(t, err)
+	hashB, err := CanonicalHash([]byte(b))
+	require.NoError(t, err)
+	hashC, err := CanonicalHash([]byte(c))
+	require.NoError(t, err)
+
+	require.Equal(t, hashA, hashB)
+	require.NotEqual(t, hashA, hashC)
+}
+
+func TestSnapshotStore(t *testing.T) {
+	store := SnapshotStore{Dir: t.TempDir()}
+
+	_, err := store.Latest()
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	first, saved, err := store.Save([]byte("kind: Config\n"), "/a", true)
+	require.NoError(t, err)
+	require.True(t, saved)
+
+	_, saved, err = store.Save([]byte("kind:   Config\n"), "/a", true)
+	require.NoError(t, err)
+	require.False(t, saved)
+
+	second, saved, err := store.Save([]byte("kind: Config\napiVersion: v1\n"), "/a", true)
+	require.NoError(t, err)
+	require.True(t, saved)
+
+	_, saved, err = store.Save([]byte("kind: Config\n"), "/b", true)
+	require.NoError(t, err)
+	require.True(t, saved)
+
+	_, saved, err = store.Save([]byte("kind: Config\napiVersion: v1\n"), "/a", true)
+	require.NoError(t, err)
+	require.False(t, saved)
+
+	objects, err := os.ReadDir(filepath.Join(store.Dir, DefaultSnapshotsFolder))
+	require.NoError(t, err)
+	require.Len(t, objects, 2)
+
+	snapshots, err := store.List()
+	require.NoError(t, err)
+	require.Len(t, snapshots, 3)
+	require.Equal(t, first.ID, snapshots[0].ID)
+	require.Equal(t, "/b", snapshots[0].Source)
+
+	found, err := store.Find(second.ShortID())
+	require.NoError(t, err)
+	require.Equal(t, second.ID, found.ID)
+
+	raw, err := store.Read(found)
+	require.NoError(t, err)
+	require.Equal(t, "kind: Config\napiVersion: v1\n", string(raw))
+
+	_, err = store.Find("zzz")
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestSnapshotStoreConcurrentSave(t *testing.T) {
+	store := SnapshotStore{Dir: t.TempDir()}
+
+	const count = 20
+	errs := make(chan error, count)
+	for i := 0; i < count; i++ {
+		go func(i int) {
+			_, _, err := store.Save([]byte(fmt.Sprintf("kind: Config\ncurrent-context: c%d\n", i)), "/a", false)
+			errs <- err
+		}(i)
+	}
+
+	for i := 0; i < count; i++ {
+		require.NoError(t, <-errs)
+	}
+
+	snapshots, err := store.List()
+	require.NoError(t, err)
+	require.Len(t, snapshots, count)
+}
+
+func TestSnapshotLatestFrom(t *testing.T) {
+	store := SnapshotStore{Dir: t.TempDir()}
+
+	first, _, err := store.Save([]byte("kind: Config\ncurrent-context: a\n"), "/home/a/config", false)
+	require.NoError(t, err)
+
+	second, _, err := store.Save([]byte("kind: Config\ncurrent-context: b\n"), "/home/b/config", false)
+	require.NoError(t, err)
+
+	latest, err := store.Latest()
+	require.NoError(t, err)
+	require.Equal(t, second.ID, latest.ID)
+
+	latest, err = store.LatestFrom("/home/a/config")
+	require.NoError(t, err)
+	require.Equal(t, first.ID, latest.ID)
+	require.Equal(t, "/home/a/config", latest.Source)
+
+	_, err = store.LatestFrom("/home/c/config")
+	require.Equal(t, ExitNotFound, ExitCode(err))
+}
+
+func TestSnapshotRestoreRebasesPaths(t *testing.T) {
+	store := SnapshotStore{Dir: t.TempDir()}
+	root := t.TempDir()
+
+	raw := `# restored as is
+apiVersion: v1
+kind: Config
+users:
+- name: oidc
+  user:
+    client-key: certs/key.pem
+    exec:
+      command: ./bin/kubelogin
+    auth-provider:
+      name: oidc
+`
+	snapshot, _, err := store.Save([]byte(raw), filepath.Join(root, "old", "config"), false)
+	require.NoError(t, err)
+
+	path := filepath.Join(root, "old", "config")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+	require.NoError(t, store.Restore(snapshot, path))
+
+	restored, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, raw, string(restored))
+
+	path = filepath.Join(root, "new", "config")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+	require.NoError(t, store.Restore(snapshot, path))
+
+	restored, err = os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, `# restored as is
+apiVersion: v1
+kind: Config
+users:
+- name: oidc
+  user:
+    client-key: `+filepath.Join("..", "old", "certs", "key.pem")+`
+    exec:
+      command: `+filepath.Join("..", "old", "bin", "kubelogin")+`
+    auth-provider:
+      name: oidc
+`, string(restored))
+}
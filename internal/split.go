@@ -0,0 +1,134 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultSplitTemplate is default template of names of files produced by split
+const DefaultSplitTemplate = "{{.Context}}.yaml"
+
+// SplitNameData is data available in template of split file names.
+// Names are made safe to be used in file names
+type SplitNameData struct {
+	Context string
+	Cluster string
+	User    string
+}
+
+// SplitFile is kubeconfig of single context produced by Split
+type SplitFile struct {
+	Path    string
+	Context string
+	Config  Kubeconfig
+}
+
+// Split produces minimal self-contained kubeconfig for every context of k, which
+// is stored in kubeconfig folder k.Dir. Files are named after nameTemplate and placed in dir
+func Split(k Kubeconfig, dir, nameTemplate string) ([]SplitFile, error) {
+	tmpl, err := template.New("name").Option("missingkey=error").Parse(nameTemplate)
+	if err != nil {
+		return nil, WithExitCode(ExitUsage, fmt.Errorf("invalid file name template: %w", err))
+	}
+
+	files := []SplitFile{}
+	paths := map[string]string{}
+
+	for _, context := range k.Contexts {
+		config, err := Minify(k, context.Name)
+		if err == nil {
+			config, err = Flatten(config, k.Dir)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("context %s: %w", context.Name, err)
+		}
+
+		name := bytes.Buffer{}
+		err = tmpl.Execute(&name, SplitNameData{
+			Context: SafeFileName(context.Name),
+			Cluster: SafeFileName(context.Context.Cluster),
+			User:    SafeFileName(context.Context.User),
+		})
+		if err != nil {
+			return nil, WithExitCode(ExitUsage, fmt.Errorf("invalid file name template: %w", err))
+		}
+
+		path := filepath.Join(dir, name.String())
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			return nil, WithExitCode(ExitUsage, fmt.Errorf("file name %q of context %s is outside of %s", name.String(), context.Name, dir))
+		}
+
+		if other, ok := paths[path]; ok {
+			return nil, WithExitCode(ExitConflict, fmt.Errorf("contexts %s and %s are both split to %s", other, context.Name, path))
+		}
+		paths[path] = context.Name
+
+		files = append(files, SplitFile{Path: path, Context: context.Name, Config: config})
+	}
+
+	return files, nil
+}
+
+// WriteSplitFiles stores kubeconfigs produced by Split, each while holding lock on it,
+// creating folders for them readable by owner only. If a file cannot be written, files
+// written before are rolled back. Kubeconfig being split, which is found at source,
+// is never overwritten. Returned changes allow to roll files back if source update fails
+func WriteSplitFiles(files []SplitFile, source string, timeout time.Duration) (*FileChanges, error) {
+	sameFile := func(a, b string) (bool, error) {
+		a, err := ResolveSymlinks(a)
+		if err != nil {
+			return false, err
+		}
+
+		b, err = ResolveSymlinks(b)
+		if err != nil {
+			return false, err
+		}
+
+		a, err = filepath.Abs(a)
+		if err != nil {
+			return false, err
+		}
+
+		b, err = filepath.Abs(b)
+		return a == b, err
+	}
+
+	for _, file := range files {
+		same, err := sameFile(file.Path, source)
+		if err != nil {
+			return nil, err
+		}
+
+		if same {
+			return nil, WithExitCode(ExitUsage, fmt.Errorf("context %s would be split to kubeconfig being split %s", file.Context, source))
+		}
+	}
+
+	changes := &FileChanges{}
+	for _, file := range files {
+		err := os.MkdirAll(filepath.Dir(file.Path), os.FileMode(0700))
+		if err == nil {
+			err = WithLock(file.Path, timeout, func() error {
+				raw, err := marshalConf(file.Path, file.Config)
+				if err != nil {
+					return err
+				}
+
+				return changes.Write(file.Path, raw, os.FileMode(0600))
+			})
+		}
+		if err != nil {
+			changes.Rollback()
+			return nil, err
+		}
+	}
+
+	return changes, nil
+}
@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplit(t *testing.T) {
+	dir := t.TempDir()
+
+	config := Kubeconfig{
+		APIVersion: "v1",
+		Kind:       "Config",
+		Clusters: []ClusterEntry{
+			{Name: "arn:aws:eks:eu-west-1:123:cluster/prod", Cluster: Cluster{Server: "https://prod"}},
+			{Name: "dev", Cluster: Cluster{Server: "https://dev"}},
+		},
+		Contexts: []ContextEntry{
+			{Name: "prod", Context: Context{Cluster: "arn:aws:eks:eu-west-1:123:cluster/prod", User: "admin"}},
+			{Name: "dev", Context: Context{Cluster: "dev", User: "admin"}},
+		},
+		Users: []UserEntry{
+			{Name: "admin", User: User{Token: "abc"}},
+		},
+		CurrentContext: "dev",
+	}
+
+	files, err := Split(config, dir, DefaultSplitTemplate)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	require.Equal(t, filepath.Join(dir, "prod.yaml"), files[0].Path)
+	require.Equal(t, "prod", files[0].Config.CurrentContext)
+	require.Len(t, files[0].Config.Clusters, 1)
+	require.Len(t, files[0].Config.Users, 1)
+
+	files, err = Split(config, dir, "{{.Cluster}}/{{.User}}.yaml")
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dir, "arn_aws_eks_eu-west-1_123_cluster_prod", "admin.yaml"), files[0].Path)
+
+	_, err = Split(config, dir, "{{.User}}.yaml")
+	require.Equal(t, ExitConflict, ExitCode(err))
+
+	_, err = Split(config, dir, "../{{.Context}}.yaml")
+	require.Equal(t, ExitUsage, ExitCode(err))
+
+	_, err = Split(config, dir, "{{.Namespace}}.yaml")
+	require.Equal(t, ExitUsage, ExitCode(err))
+}
+
+func TestWriteSplitFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "split")
+	source := filepath.Join(dir, "dev.yaml")
+
+	config := Kubeconfig{
+		APIVersion: "v1",
+		Kind:       "Config",
+		Clusters:   []ClusterEntry{{Name: "dev", Cluster: Cluster{Server: "https://dev"}}},
+		Contexts: []ContextEntry{
+			{Name: "prod", Context: Context{Cluster: "dev", User: "admin"}},
+			{Name: "dev", Context: Context{Cluster: "dev", User: "admin"}},
+		},
+		Users: []UserEntry{{Name: "admin", User: User{Token: "abc"}}},
+	}
+
+	files, err := Split(config, dir, DefaultSplitTemplate)
+	require.NoError(t, err)
+
+	_, err = WriteSplitFiles(files, source, DefaultLockTimeout)
+	require.Equal(t, ExitUsage, ExitCode(err))
+	require.NoDirExists(t, dir)
+
+	files, err = Split(config, dir, "{{.Context}}/config")
+	require.NoError(t, err)
+
+	written, err := WriteSplitFiles(files, source, DefaultLockTimeout)
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join(dir, "prod", "config"), filepath.Join(dir, "dev", "config")}, written.Paths())
+
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(filepath.Join(dir, "prod"))
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0700), info.Mode().Perm())
+	}
+
+	// file which cannot be written rolls back the ones written before it
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "prod", "config"), []byte("previous"), 0600))
+	require.NoError(t, os.Remove(filepath.Join(dir, "dev", "config")))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "dev", "config"), 0700))
+
+	_, err = WriteSplitFiles(files, source, DefaultLockTimeout)
+	require.Error(t, err)
+
+	previous, err := os.ReadFile(filepath.Join(dir, "prod", "config"))
+	require.NoError(t, err)
+	require.Equal(t, "previous", string(previous))
+}
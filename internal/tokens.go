@@ -0,0 +1,191 @@
+package internal
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// Audience is aud claim of JWT which is either single string or list of strings
+type Audience []string
+
+// UnmarshalJSON implements json.Unmarshaler
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+
+	*a = list
+	return nil
+}
+
+// JWTClaims represents claims of JWT relevant to kubernetes
+type JWTClaims struct {
+	Issuer    string   `json:"iss"`
+	Subject   string   `json:"sub"`
+	Audience  Audience `json:"aud"`
+	ExpiresAt *float64 `json:"exp"`
+	IssuedAt  *float64 `json:"iat"`
+
+	// bound service account tokens
+	Kubernetes *struct {
+		Namespace      string `json:"namespace"`
+		ServiceAccount struct {
+			Name string `json:"name"`
+		} `json:"serviceaccount"`
+	} `json:"kubernetes.io"`
+
+	// legacy service account tokens stored in secrets
+	LegacyNamespace      string `json:"kubernetes.io/serviceaccount/namespace"`
+	LegacyServiceAccount string `json:"kubernetes.io/serviceaccount/service-account.name"`
+}
+
+// DecodeJWT decodes claims of JWT without verifying its signature
+func DecodeJWT(token string) (JWTClaims, error) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 {
+		return JWTClaims{}, errors.New("token is not a JWT")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return JWTClaims{}, fmt.Errorf("cannot decode JWT payload: %w", err)
+	}
+
+	claims := JWTClaims{}
+	err = json.Unmarshal(payload, &claims)
+	if err != nil {
+		return JWTClaims{}, fmt.Errorf("cannot decode JWT claims: %w", err)
+	}
+
+	return claims, nil
+}
+
+// TokenInfo describes bearer token of kubeconfig user. It never contains token itself
+type TokenInfo struct {
+	User           string     `json:"user"`
+	Field          string     `json:"field"`
+	Fingerprint    string     `json:"fingerprint,omitempty"`
+	Issuer         string     `json:"issuer,omitempty"`
+	Subject        string     `json:"subject,omitempty"`
+	Audience       []string   `json:"audience,omitempty"`
+	Namespace      string     `json:"namespace,omitempty"`
+	ServiceAccount string     `json:"serviceAccount,omitempty"`
+	IssuedAt       *time.Time `json:"issuedAt,omitempty"`
+	ExpiresAt      *time.Time `json:"expiresAt,omitempty"`
+	Expired        bool       `json:"expired"`
+	Error          string     `json:"error,omitempty"`
+}
+
+// InspectTokens decodes bearer tokens of every user, either inline or stored
+// in token file referenced by path relative to dir
+func InspectTokens(k Kubeconfig, dir string) []TokenInfo {
+	infos := []TokenInfo{}
+
+	for _, entry := range k.Users {
+		if entry.User.Token != "" {
+			infos = append(infos, DescribeToken(entry.Name, "token", entry.User.Token))
+		}
+
+		if entry.User.TokenFile != "" {
+			raw, err := os.ReadFile(ResolvePath(dir, entry.User.TokenFile))
+			if err != nil {
+				infos = append(infos, TokenInfo{User: entry.Name, Field: "tokenFile", Error: err.Error()})
+				continue
+			}
+
+			infos = append(infos, DescribeToken(entry.Name, "tokenFile", strings.TrimSpace(string(raw))))
+		}
+	}
+
+	return infos
+}
+
+// DescribeToken returns details of token of user
+func DescribeToken(user, field, token string) TokenInfo {
+	info := TokenInfo{User: user, Field: field, Fingerprint: Fingerprint([]byte(token))}
+
+	claims, err := DecodeJWT(token)
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+
+	info.Issuer = claims.Issuer
+	info.Subject = claims.Subject
+	info.Audience = claims.Audience
+	info.Namespace = claims.LegacyNamespace
+	info.ServiceAccount = claims.LegacyServiceAccount
+
+	if claims.Kubernetes != nil {
+		info.Namespace = claims.Kubernetes.Namespace
+		info.ServiceAccount = claims.Kubernetes.ServiceAccount.Name
+	}
+
+	if claims.IssuedAt != nil {
+		issued := time.Unix(int64(*claims.IssuedAt), 0)
+		info.IssuedAt = &issued
+	}
+
+	if claims.ExpiresAt != nil {
+		expires := time.Unix(int64(*claims.ExpiresAt), 0)
+		info.ExpiresAt = &expires
+		info.Expired = time.Now().After(expires)
+	}
+
+	return info
+}
+
+// PrintTokens prints token details, highlighting expired ones
+func PrintTokens(infos []TokenInfo) {
+	magenta := color.New(color.FgMagenta)
+	red := color.New(color.FgRed)
+
+	for _, info := range infos {
+		magenta.Printf("user %s %s\n", info.User, info.Field)
+
+		if info.Fingerprint != "" {
+			fmt.Printf("  fingerprint:     %s\n", info.Fingerprint)
+		}
+
+		if info.Error != "" {
+			fmt.Printf("  details:         %s\n", info.Error)
+			continue
+		}
+
+		fmt.Printf("  issuer:          %s\n", info.Issuer)
+		fmt.Printf("  subject:         %s\n", info.Subject)
+		if len(info.Audience) > 0 {
+			fmt.Printf("  audience:        %s\n", strings.Join(info.Audience, ", "))
+		}
+		if info.ServiceAccount != "" {
+			fmt.Printf("  service account: %s/%s\n", info.Namespace, info.ServiceAccount)
+		}
+		if info.IssuedAt != nil {
+			fmt.Printf("  issued at:       %s\n", info.IssuedAt.Local().Format(time.RFC3339))
+		}
+
+		switch {
+		case info.ExpiresAt == nil:
+			fmt.Printf("  expires at:      never\n")
+		case info.Expired:
+			red.Printf("  expires at:      %s (expired)\n", info.ExpiresAt.Local().Format(time.RFC3339))
+		default:
+			fmt.Printf("  expires at:      %s (in %s)\n",
+				info.ExpiresAt.Local().Format(time.RFC3339), HumanDuration(time.Until(*info.ExpiresAt)))
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testJWT(t *testing.T, claims map[string]interface{}) string {
+	t.Helper()
+
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	return header + "." + base64.RawURLEncoding.EncodeToString(payload) + ".c2lnbmF0dXJl"
+}
+
+func TestInspectTokens(t *testing.T) {
+	dir := t.TempDir()
+
+	bound := testJWT(t, map[string]interface{}{
+		"iss": "https://kubernetes.default.svc",
+		"sub": "system:serviceaccount:ci:deployer",
+		"aud": []string{"https://kubernetes.default.svc"},
+		"iat": time.Now().Add(-time.Hour).Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+		"kubernetes.io": map[string]interface{}{
+			"namespace":      "ci",
+			"serviceaccount": map[string]interface{}{"name": "deployer"},
+		},
+	})
+
+	legacy := testJWT(t, map[string]interface{}{
+		"iss":                                    "kubernetes/serviceaccount",
+		"sub":                                    "system:serviceaccount:default:old",
+		"kubernetes.io/serviceaccount/namespace": "default",
+		"kubernetes.io/serviceaccount/service-account.name": "old",
+	})
+
+	expired := testJWT(t, map[string]interface{}{
+		"iss": "https://accounts.google.com",
+		"aud": "kubernetes",
+		"exp": time.Now().Add(-time.Hour).Unix(),
+	})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "token"), []byte(expired+"\n"), 0600))
+
+	config := Kubeconfig{
+		Users: []UserEntry{
+			{Name: "bound", User: User{Token: bound}},
+			{Name: "legacy", User: User{Token: legacy}},
+			{Name: "oidc", User: User{TokenFile: "token"}},
+			{Name: "static", User: User{Token: "kubeconfig-user-fffffff:123"}},
+		},
+	}
+
+	infos := InspectTokens(config, dir)
+	require.Len(t, infos, 4)
+
+	require.Equal(t, "ci", infos[0].Namespace)
+	require.Equal(t, "deployer", infos[0].ServiceAccount)
+	require.Equal(t, []string{"https://kubernetes.default.svc"}, infos[0].Audience)
+	require.NotNil(t, infos[0].IssuedAt)
+	require.False(t, infos[0].Expired)
+
+	require.Equal(t, "default", infos[1].Namespace)
+	require.Equal(t, "old", infos[1].ServiceAccount)
+	require.Nil(t, infos[1].ExpiresAt)
+
+	require.Equal(t, "tokenFile", infos[2].Field)
+	require.Equal(t, []string{"kubernetes"}, infos[2].Audience)
+	require.True(t, infos[2].Expired)
+
+	require.NotEmpty(t, infos[3].Error)
+
+	raw, err := json.Marshal(infos)
+	require.NoError(t, err)
+	for _, token := range []string{bound, legacy, expired, "kubeconfig-user-fffffff:123"} {
+		require.NotContains(t, string(raw), token)
+	}
+}
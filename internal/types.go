@@ -6,6 +6,9 @@ const OptionOutput = "output"
 // OptionKubeconfig is cli flag name for setting custom kubeconfig file
 const OptionKubeconfig = "kubeconfig"
 
+// OptionLockTimeout is cli flag name for setting how long to wait for kubeconfig lock
+const OptionLockTimeout = "lock-timeout"
+
 // OptionBackup is cli flag name for setting custom backup file
 const OptionBackup = "backup"
 
@@ -18,17 +21,210 @@ const DefaultBackupFolder = ".konfig"
 // DefaultKubeconfigFile is default backup file name
 const DefaultKubeconfigFile = "config"
 
+// OptionBundle is cli flag name for setting a portable backup bundle
+const OptionBundle = "bundle"
+
+// OptionBundleDir is cli flag name for setting a folder where bundle files are unpacked
+const OptionBundleDir = "bundle-dir"
+
+// OptionIfChanged is cli flag name for skipping backup when kubeconfig has not changed
+const OptionIfChanged = "if-changed"
+
+// OptionList is cli flag name for listing stored snapshots
+const OptionList = "list"
+
+// OptionSnapshot is cli flag name for selecting snapshot by id
+const OptionSnapshot = "snapshot"
+
+// FormatText is output format for humans
+const FormatText = "text"
+
+// FormatJSON is output format for scripts
+const FormatJSON = "json"
+
+// FormatSARIF is output format for code scanning tools
+const FormatSARIF = "sarif"
+
+// OptionIgnore is cli flag name for setting list of ignored audit rules
+const OptionIgnore = "ignore"
+
+// OptionExpiringWithin is cli flag name for setting how soon certificates must expire to be reported
+const OptionExpiringWithin = "expiring-within"
+
+// OptionContext is cli flag name for selecting context
+const OptionContext = "context"
+
+// OptionMinify is cli flag name for keeping only one context in kubeconfig
+const OptionMinify = "minify"
+
+// OptionDir is cli flag name for setting folder to store files to
+const OptionDir = "dir"
+
+// OptionTemplate is cli flag name for setting template of file names
+const OptionTemplate = "template"
+
+// OptionDelete is cli flag name for deleting processed entries from source kubeconfig
+const OptionDelete = "delete"
+
+// OptionFlatten is cli flag name for inlining referenced files
+const OptionFlatten = "flatten"
+
+// OptionRedact is cli flag name for hiding secrets
+const OptionRedact = "redact"
+
+// OptionNamespace is cli flag name for setting namespace of context
+const OptionNamespace = "namespace"
+
+// OptionRegex is cli flag name for treating patterns as regular expressions
+const OptionRegex = "regex"
+
+// OptionKeepOrphans is cli flag name for keeping clusters and users no context uses
+const OptionKeepOrphans = "keep-orphans"
+
+// OptionCurrent is cli flag name for context to make current
+const OptionCurrent = "current"
+
+// OptionYes is cli flag name for applying changes without confirmation
+const OptionYes = "yes"
+
+// OptionDryRun is cli flag name for previewing changes without applying them
+const OptionDryRun = "dry-run"
+
+// OptionFromFile is cli flag name for file with renames
+const OptionFromFile = "from-file"
+
+// OptionUser is cli flag name for user of context
+const OptionUser = "user"
+
+// OptionAs is cli flag name for user to impersonate
+const OptionAs = "as"
+
+// OptionAsGroup is cli flag name for group to impersonate
+const OptionAsGroup = "as-group"
+
+// OptionSetCurrent is cli flag name for making created context current
+const OptionSetCurrent = "set-current"
+
+// OptionServer is cli flag name for API server URL
+const OptionServer = "server"
+
+// OptionName is cli flag name for name of created entries
+const OptionName = "name"
+
+// OptionForce is cli flag name for replacing existing entries
+const OptionForce = "force"
+
+// OptionRoot is cli flag name for folder service account mount is searched in
+const OptionRoot = "root"
+
+// OptionCAFile is cli flag name for CA certificate file
+const OptionCAFile = "ca-file"
+
+// OptionInsecure is cli flag name for skipping verification of server certificate
+const OptionInsecure = "insecure-skip-tls-verify"
+
+// OptionEmbed is cli flag name for inlining files instead of referencing them
+const OptionEmbed = "embed"
+
+// OptionToken is cli flag name for bearer token
+const OptionToken = "token"
+
+// OptionTokenFile is cli flag name for file with bearer token
+const OptionTokenFile = "token-file"
+
+// OptionClientCert is cli flag name for client certificate file
+const OptionClientCert = "client-cert"
+
+// OptionClientKey is cli flag name for client key file
+const OptionClientKey = "client-key"
+
+// OptionExecCommand is cli flag name for exec credential plugin command
+const OptionExecCommand = "exec-command"
+
+// OptionExecArg is cli flag name for argument of exec credential plugin
+const OptionExecArg = "exec-arg"
+
+// OptionExecEnv is cli flag name for environment variable of exec credential plugin
+const OptionExecEnv = "exec-env"
+
+// OptionExecAPIVersion is cli flag name for apiVersion of exec credential plugin
+const OptionExecAPIVersion = "exec-api-version"
+
+// OptionCluster is cli flag name for cluster of context
+const OptionCluster = "cluster"
+
+// OptionGroup is cli flag name for group user belongs to
+const OptionGroup = "group"
+
+// OptionKeyType is cli flag name for type of generated private key
+const OptionKeyType = "key-type"
+
+// OptionSubmit is cli flag name for submitting certificate signing request to cluster
+const OptionSubmit = "submit"
+
+// OptionApprove is cli flag name for approving submitted certificate signing request
+const OptionApprove = "approve"
+
+// OptionWait is cli flag name for how long to wait for certificate to be signed
+const OptionWait = "wait"
+
+// OptionExpiration is cli flag name for requested lifetime of certificate
+const OptionExpiration = "expiration"
+
+// BundleConfigName is name of kubeconfig file inside backup bundle
+const BundleConfigName = "config"
+
+// BundleFilesFolder is folder inside backup bundle where referenced files are stored
+const BundleFilesFolder = "files"
+
 // Context represents k8s context section of kubectl config file
 type Context struct {
-	Cluster string `yaml:"cluster"`
-	User    string `yaml:"user"`
+	Cluster    string           `yaml:"cluster"`
+	User       string           `yaml:"user"`
+	Namespace  string           `yaml:"namespace,omitempty"`
+	Extensions []ExtensionEntry `yaml:"extensions,omitempty"`
 }
 
 // User represents k8s user section of kubectl config file
 type User struct {
-	ClientCertificateData string `yaml:"client-certificate-data,omitempty"`
-	ClientKeyData         string `yaml:"client-key-data,omitempty"`
-	Token                 string `yaml:"token,omitempty"`
+	ClientCertificateData string              `yaml:"client-certificate-data,omitempty"`
+	ClientKeyData         string              `yaml:"client-key-data,omitempty"`
+	Token                 string              `yaml:"token,omitempty"`
+	ClientCertificate     string              `yaml:"client-certificate,omitempty"`
+	ClientKey             string              `yaml:"client-key,omitempty"`
+	TokenFile             string              `yaml:"tokenFile,omitempty"`
+	Username              string              `yaml:"username,omitempty"`
+	Password              string              `yaml:"password,omitempty"`
+	Exec                  *ExecConfig         `yaml:"exec,omitempty"`
+	AuthProvider          *AuthProviderConfig `yaml:"auth-provider,omitempty"`
+	As                    string              `yaml:"as,omitempty"`
+	AsUID                 string              `yaml:"as-uid,omitempty"`
+	AsGroups              []string            `yaml:"as-groups,omitempty"`
+	AsUserExtra           map[string][]string `yaml:"as-user-extra,omitempty"`
+	Extensions            []ExtensionEntry    `yaml:"extensions,omitempty"`
+}
+
+// AuthProviderConfig represents auth provider plugin section of user, like oidc
+type AuthProviderConfig struct {
+	Name   string            `yaml:"name"`
+	Config map[string]string `yaml:"config,omitempty"`
+}
+
+// ExecEnvVar represents environment variable passed to exec credential plugin
+type ExecEnvVar struct {
+	Name  string `yaml:"name"`
+	Value string `yaml:"value"`
+}
+
+// ExecConfig represents exec credential plugin section of user
+type ExecConfig struct {
+	APIVersion         string       `yaml:"apiVersion,omitempty"`
+	Command            string       `yaml:"command"`
+	Args               []string     `yaml:"args,omitempty"`
+	Env                []ExecEnvVar `yaml:"env,omitempty"`
+	InstallHint        string       `yaml:"installHint,omitempty"`
+	ProvideClusterInfo bool         `yaml:"provideClusterInfo,omitempty"`
+	InteractiveMode    string       `yaml:"interactiveMode,omitempty"`
 }
 
 // Extension represents k8s extension section of kubectl config file
@@ -47,9 +243,13 @@ type ExtensionEntry struct {
 // Cluster represents k8s cluster section of kubectl config file
 type Cluster struct {
 	Server                   string           `yaml:"server"`
-	CertificateAuthorityData string           `yaml:"certificate-authority-data"`
-	Extensions               []ExtensionEntry `yaml:"extensions"`
-	CertificateAuthority     string           `yaml:"certificate-authority"`
+	CertificateAuthorityData string           `yaml:"certificate-authority-data,omitempty"`
+	Extensions               []ExtensionEntry `yaml:"extensions,omitempty"`
+	CertificateAuthority     string           `yaml:"certificate-authority,omitempty"`
+	InsecureSkipTLSVerify    bool             `yaml:"insecure-skip-tls-verify,omitempty"`
+	TLSServerName            string           `yaml:"tls-server-name,omitempty"`
+	ProxyURL                 string           `yaml:"proxy-url,omitempty"`
+	DisableCompression       bool             `yaml:"disable-compression,omitempty"`
 }
 
 // ClusterEntry represents list of clusters in kubectl config file
@@ -82,4 +282,8 @@ type Kubeconfig struct {
 	CurrentContext string         `yaml:"current-context"`
 	Users          []UserEntry    `yaml:"users"`
 	Preferences    Preferences    `yaml:"preferences,omitempty"`
+
+	// Dir is absolute path of folder kubeconfig was read from, relative
+	// file references are resolved against it. Empty if unknown
+	Dir string `yaml:"-"`
 }
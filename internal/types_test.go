@@ -222,6 +222,15 @@ func TestKubeconfig(t *testing.T) {
 							ClientCertificateData: "",
 							ClientKeyData:         "",
 							Token:                 "",
+							AuthProvider: &AuthProviderConfig{
+								Name: "gcp",
+								Config: map[string]string{
+									"cmd-args":   "config config-helper --format=json",
+									"cmd-path":   "/foo/bar",
+									"expiry-key": "{.credential.token_expiry}",
+									"token-key":  "{.credential.access_token}",
+								},
+							},
 						},
 					},
 				},
@@ -292,8 +301,19 @@ func TestKubeconfig(t *testing.T) {
 					{
 						Name: "",
 						Context: Context{
-							Cluster: "minikube",
-							User:    "minikube",
+							Cluster:   "minikube",
+							User:      "minikube",
+							Namespace: "default",
+							Extensions: []ExtensionEntry{
+								{
+									Name: "",
+									Extension: Extension{
+										Provider:   "minikube.sigs.k8s.io",
+										Version:    "v1.25.2",
+										LastUpdate: "Thu, 11 Aug 2022 16:22:25 +04",
+									},
+								},
+							},
 						},
 					},
 				},
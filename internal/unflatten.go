@@ -0,0 +1,137 @@
+package internal
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+)
+
+// Unflatten is reverse of Flatten: it writes every inline certificate authority,
+// client certificate and client key of kubeconfig stored in kubeconfigDir into
+// files in certDir, readable by owner only, and replaces data fields with paths
+// to them. Existing files are only replaced if overwrite is set, otherwise free
+// names are picked. Result is checked to load identically to original kubeconfig.
+// On error files written so far are rolled back, replaced ones get previous content back
+func Unflatten(k Kubeconfig, kubeconfigDir, certDir string, overwrite bool) (result Kubeconfig, files *FileChanges, err error) {
+	certDir, err = filepath.Abs(certDir)
+	if err != nil {
+		return Kubeconfig{}, nil, err
+	}
+
+	expected, err := Flatten(k, kubeconfigDir)
+	if err != nil {
+		return Kubeconfig{}, nil, err
+	}
+
+	u := unflattener{dir: certDir, overwrite: overwrite, used: map[string]bool{}, files: &FileChanges{}}
+	defer func() {
+		if err != nil {
+			u.files.Rollback()
+		}
+	}()
+
+	result = k
+	result.Clusters = append([]ClusterEntry{}, k.Clusters...)
+	result.Users = append([]UserEntry{}, k.Users...)
+
+	for i := range result.Clusters {
+		entry := &result.Clusters[i]
+
+		err = u.extract(entry.Name+"-ca.crt", &entry.Cluster.CertificateAuthority, &entry.Cluster.CertificateAuthorityData)
+		if err != nil {
+			return Kubeconfig{}, nil, fmt.Errorf("cluster %s: %w", entry.Name, err)
+		}
+	}
+
+	for i := range result.Users {
+		entry := &result.Users[i]
+
+		err = u.extract(entry.Name+".crt", &entry.User.ClientCertificate, &entry.User.ClientCertificateData)
+		if err == nil {
+			err = u.extract(entry.Name+".key", &entry.User.ClientKey, &entry.User.ClientKeyData)
+		}
+		if err != nil {
+			return Kubeconfig{}, nil, fmt.Errorf("user %s: %w", entry.Name, err)
+		}
+	}
+
+	actual, err := Flatten(result, kubeconfigDir)
+	if err != nil {
+		return Kubeconfig{}, nil, err
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		return Kubeconfig{}, nil, errors.New("kubeconfig with extracted files differs from original one")
+	}
+
+	return result, u.files, nil
+}
+
+type unflattener struct {
+	dir       string
+	overwrite bool
+	used      map[string]bool
+	files     *FileChanges
+}
+
+func (u *unflattener) extract(name string, path, data *string) error {
+	if *data == "" {
+		return nil
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(*data)
+	if err != nil {
+		return WithExitCode(ExitValidation, fmt.Errorf("invalid base64: %w", err))
+	}
+
+	err = os.MkdirAll(u.dir, os.FileMode(0700))
+	if err != nil {
+		return err
+	}
+
+	file := filepath.Join(u.dir, u.uniqueName(name))
+
+	err = u.files.Write(file, raw, os.FileMode(0600))
+	if err != nil {
+		return err
+	}
+
+	// existing files keep their mode on write, so tighten it explicitly
+	err = os.Chmod(file, os.FileMode(0600))
+	if err != nil {
+		return err
+	}
+
+	*path = file
+	*data = ""
+
+	return nil
+}
+
+// uniqueName turns entry name into safe file name, which is not used by other
+// entries and, unless files may be overwritten, by existing files
+func (u *unflattener) uniqueName(name string) string {
+	name = SafeFileName(name)
+
+	ext := filepath.Ext(name)
+	base := name[:len(name)-len(ext)]
+
+	taken := func(name string) bool {
+		if u.used[name] {
+			return true
+		}
+
+		_, err := os.Stat(filepath.Join(u.dir, name))
+		return !u.overwrite && !errors.Is(err, os.ErrNotExist)
+	}
+
+	for i := 2; taken(name); i++ {
+		name = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+
+	u.used[name] = true
+	return name
+}
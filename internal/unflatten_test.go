@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnflatten(t *testing.T) {
+	kubeconfigDir := t.TempDir()
+	certDir := filepath.Join(t.TempDir(), "certs")
+	encode := base64.StdEncoding.EncodeToString
+
+	config := Kubeconfig{
+		Clusters: []ClusterEntry{
+			{Name: "arn:aws:eks:eu-west-1:123:cluster/prod", Cluster: Cluster{CertificateAuthorityData: encode([]byte("ca"))}},
+			{Name: "arn:aws:eks:eu-west-1:123:cluster:prod", Cluster: Cluster{CertificateAuthorityData: encode([]byte("ca2"))}},
+		},
+		Users: []UserEntry{
+			{Name: "admin", User: User{
+				ClientCertificateData: encode([]byte("cert")),
+				ClientKeyData:         encode([]byte("key")),
+			}},
+			{Name: "token", User: User{Token: "abc"}},
+		},
+	}
+
+	require.NoError(t, os.MkdirAll(certDir, 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(certDir, "admin.crt"), []byte("other"), 0600))
+
+	result, files, err := Unflatten(config, kubeconfigDir, certDir, false)
+	require.NoError(t, err)
+	require.Len(t, files.Paths(), 4)
+	require.Equal(t, encode([]byte("ca")), config.Clusters[0].Cluster.CertificateAuthorityData, "input must not be modified")
+
+	require.Empty(t, result.Clusters[0].Cluster.CertificateAuthorityData)
+	require.Equal(t, filepath.Join(certDir, "arn_aws_eks_eu-west-1_123_cluster_prod-ca.crt"), result.Clusters[0].Cluster.CertificateAuthority)
+	require.Equal(t, filepath.Join(certDir, "arn_aws_eks_eu-west-1_123_cluster_prod-ca-2.crt"), result.Clusters[1].Cluster.CertificateAuthority)
+	require.Equal(t, filepath.Join(certDir, "admin-2.crt"), result.Users[0].User.ClientCertificate)
+	require.Equal(t, filepath.Join(certDir, "admin.key"), result.Users[0].User.ClientKey)
+	require.Equal(t, "abc", result.Users[1].User.Token)
+
+	for _, file := range files.Paths() {
+		info, err := os.Stat(file)
+		require.NoError(t, err)
+		if runtime.GOOS != "windows" {
+			require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+		}
+	}
+
+	flat, err := Flatten(result, kubeconfigDir)
+	require.NoError(t, err)
+	require.Equal(t, config, flat)
+
+	other, err := os.ReadFile(filepath.Join(certDir, "admin.crt"))
+	require.NoError(t, err)
+	require.Equal(t, "other", string(other))
+
+	_, files, err = Unflatten(Kubeconfig{Users: []UserEntry{{Name: "x", User: User{
+		ClientCertificateData: encode([]byte("cert")),
+		ClientKeyData:         "!",
+	}}}}, kubeconfigDir, certDir, false)
+	require.Equal(t, ExitValidation, ExitCode(err))
+	require.Nil(t, files)
+	require.NoFileExists(t, filepath.Join(certDir, "x.crt"))
+
+	// files existing before are given their content back on failure, not removed
+	_, files, err = Unflatten(Kubeconfig{Users: []UserEntry{{Name: "admin", User: User{
+		ClientCertificateData: encode([]byte("new cert")),
+		ClientKeyData:         "!",
+	}}}}, kubeconfigDir, certDir, true)
+	require.Equal(t, ExitValidation, ExitCode(err))
+	require.Nil(t, files)
+
+	other, err = os.ReadFile(filepath.Join(certDir, "admin.crt"))
+	require.NoError(t, err)
+	require.Equal(t, "other", string(other))
+
+	_, files, err = Unflatten(config, kubeconfigDir, certDir, true)
+	require.NoError(t, err)
+	require.Contains(t, files.Paths(), filepath.Join(certDir, "admin.crt"))
+
+	files.Rollback()
+	other, err = os.ReadFile(filepath.Join(certDir, "admin.crt"))
+	require.NoError(t, err)
+	require.Equal(t, "other", string(other))
+	require.FileExists(t, filepath.Join(certDir, "admin.key"))
+}
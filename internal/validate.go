@@ -0,0 +1,305 @@
+package internal
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// Severity is severity of problem found in kubeconfig
+type Severity string
+
+const (
+	// SeverityError means kubectl will fail when using affected entry
+	SeverityError Severity = "error"
+	// SeverityWarning means entry is suspicious, but might work
+	SeverityWarning Severity = "warning"
+)
+
+// Finding represents single problem found in kubeconfig
+type Finding struct {
+	Rule     string   `json:"rule,omitempty"`
+	Severity Severity `json:"severity"`
+	Section  string   `json:"section"`
+	Name     string   `json:"name,omitempty"`
+	Field    string   `json:"field,omitempty"`
+	Message  string   `json:"message"`
+}
+
+// Location describes where problem is found, e.g. "cluster prod server"
+func (f Finding) Location() string {
+	location := f.Section
+	if f.Name != "" {
+		location += " " + f.Name
+	}
+	if f.Field != "" {
+		location += " " + f.Field
+	}
+
+	if f.Rule != "" {
+		location = f.Rule + " " + location
+	}
+
+	return location
+}
+
+// Validate checks structural and referential integrity of kubeconfig. Relative
+// file references are resolved against dir, folder where kubeconfig is stored
+func Validate(k Kubeconfig, dir string) []Finding {
+	v := validator{dir: dir, findings: []Finding{}}
+
+	clusters := v.checkNames("cluster", ClusterNames(k))
+	contexts := v.checkNames("context", ContextNames(k))
+	users := v.checkNames("user", UserNames(k))
+
+	if k.CurrentContext == "" && len(k.Contexts) > 0 {
+		v.add(SeverityWarning, "current-context", "", "", "current-context is not set")
+	} else if k.CurrentContext != "" && !contexts[k.CurrentContext] {
+		v.add(SeverityError, "current-context", "", "", fmt.Sprintf("context %q not found", k.CurrentContext))
+	}
+
+	for _, entry := range k.Contexts {
+		switch {
+		case entry.Context.Cluster == "":
+			v.add(SeverityError, "context", entry.Name, "cluster", "context does not reference any cluster")
+		case !clusters[entry.Context.Cluster]:
+			v.add(SeverityError, "context", entry.Name, "cluster", fmt.Sprintf("cluster %q not found", entry.Context.Cluster))
+		}
+
+		switch {
+		case entry.Context.User == "":
+			v.add(SeverityWarning, "context", entry.Name, "user", "context does not reference any user")
+		case !users[entry.Context.User]:
+			v.add(SeverityError, "context", entry.Name, "user", fmt.Sprintf("user %q not found", entry.Context.User))
+		}
+	}
+
+	for _, entry := range k.Clusters {
+		v.checkServer(entry)
+		v.checkCertificates("cluster", entry.Name, "certificate-authority", entry.Cluster.CertificateAuthority,
+			entry.Cluster.CertificateAuthorityData)
+	}
+
+	for _, entry := range k.Users {
+		user := entry.User
+
+		v.checkCertificates("user", entry.Name, "client-certificate", user.ClientCertificate, user.ClientCertificateData)
+		v.checkKey(entry.Name, user.ClientKey, user.ClientKeyData)
+
+		hasCert := user.ClientCertificate != "" || user.ClientCertificateData != ""
+		hasKey := user.ClientKey != "" || user.ClientKeyData != ""
+		if hasCert != hasKey {
+			v.add(SeverityError, "user", entry.Name, "", "client certificate and client key must be set together")
+		}
+
+		if user.TokenFile != "" {
+			v.checkFile("user", entry.Name, "tokenFile", user.TokenFile)
+		}
+
+		if user.Exec != nil {
+			v.checkExec(entry.Name, user.Exec)
+		}
+	}
+
+	return v.findings
+}
+
+type validator struct {
+	dir      string
+	findings []Finding
+}
+
+func (v *validator) add(severity Severity, section, name, field, message string) {
+	v.findings = append(v.findings, Finding{
+		Severity: severity,
+		Section:  section,
+		Name:     name,
+		Field:    field,
+		Message:  message,
+	})
+}
+
+func (v *validator) checkNames(section string, names []string) map[string]bool {
+	seen := map[string]bool{}
+
+	for _, name := range names {
+		if name == "" {
+			v.add(SeverityError, section, name, "name", section+" has no name")
+			continue
+		}
+
+		if seen[name] {
+			v.add(SeverityError, section, name, "name", "duplicate "+section+" name")
+		}
+
+		seen[name] = true
+	}
+
+	return seen
+}
+
+func (v *validator) checkServer(entry ClusterEntry) {
+	if entry.Cluster.Server == "" {
+		v.add(SeverityError, "cluster", entry.Name, "server", "server is empty")
+		return
+	}
+
+	u, err := url.Parse(entry.Cluster.Server)
+	if err != nil {
+		v.add(SeverityError, "cluster", entry.Name, "server", fmt.Sprintf("malformed server url: %s", err))
+		return
+	}
+
+	if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		v.add(SeverityError, "cluster", entry.Name, "server",
+			fmt.Sprintf("server url %q must be http(s)://host[:port]", entry.Cluster.Server))
+	}
+}
+
+// readData returns content of *-data field or of referenced file, reporting problems with both
+func (v *validator) readData(section, name, field, path, data string) ([]byte, bool) {
+	if data != "" {
+		decoded, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			v.add(SeverityError, section, name, field+"-data", fmt.Sprintf("invalid base64: %s", err))
+			return nil, false
+		}
+
+		return decoded, true
+	}
+
+	if path != "" {
+		return v.checkFile(section, name, field, path)
+	}
+
+	return nil, false
+}
+
+func (v *validator) checkFile(section, name, field, path string) ([]byte, bool) {
+	content, err := os.ReadFile(ResolvePath(v.dir, path))
+	if err != nil {
+		v.add(SeverityError, section, name, field, fmt.Sprintf("cannot read referenced file: %s", err))
+		return nil, false
+	}
+
+	return content, true
+}
+
+func (v *validator) checkCertificates(section, name, field, path, data string) {
+	content, ok := v.readData(section, name, field, path, data)
+	if !ok {
+		return
+	}
+
+	if data != "" {
+		field += "-data"
+	}
+
+	_, err := ParseCertificates(content)
+	if err != nil {
+		v.add(SeverityError, section, name, field, err.Error())
+	}
+}
+
+func (v *validator) checkKey(name, path, data string) {
+	content, ok := v.readData("user", name, "client-key", path, data)
+	if !ok {
+		return
+	}
+
+	field := "client-key"
+	if data != "" {
+		field += "-data"
+	}
+
+	_, err := ParsePrivateKey(content)
+	if err != nil {
+		v.add(SeverityError, "user", name, field, err.Error())
+	}
+}
+
+func (v *validator) checkExec(name string, e *ExecConfig) {
+	if e.Command == "" {
+		v.add(SeverityError, "user", name, "exec.command", "exec plugin command is empty")
+		return
+	}
+
+	command := e.Command
+	// client-go resolves relative plugin paths against kubeconfig folder
+	if strings.ContainsRune(command, filepath.Separator) {
+		command = ResolvePath(v.dir, command)
+	}
+
+	_, err := exec.LookPath(command)
+	if err != nil {
+		v.add(SeverityError, "user", name, "exec.command", fmt.Sprintf("exec plugin %q not found: %s", e.Command, err))
+	}
+
+	if e.APIVersion == "" {
+		v.add(SeverityWarning, "user", name, "exec.apiVersion", "exec plugin apiVersion is not set")
+	}
+}
+
+// ClusterNames returns names of all clusters of kubeconfig
+func ClusterNames(k Kubeconfig) []string {
+	names := []string{}
+	for _, entry := range k.Clusters {
+		names = append(names, entry.Name)
+	}
+
+	return names
+}
+
+// ContextNames returns names of all contexts of kubeconfig
+func ContextNames(k Kubeconfig) []string {
+	names := []string{}
+	for _, entry := range k.Contexts {
+		names = append(names, entry.Name)
+	}
+
+	return names
+}
+
+// UserNames returns names of all users of kubeconfig
+func UserNames(k Kubeconfig) []string {
+	names := []string{}
+	for _, entry := range k.Users {
+		names = append(names, entry.Name)
+	}
+
+	return names
+}
+
+// HasErrors reports whether there are findings with error severity
+func HasErrors(findings []Finding) bool {
+	for _, finding := range findings {
+		if finding.Severity == SeverityError {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PrintFindings prints findings in human readable form with colors
+func PrintFindings(findings []Finding) {
+	red := color.New(color.FgRed)
+	yellow := color.New(color.FgYellow)
+
+	for _, finding := range findings {
+		switch finding.Severity {
+		case SeverityError:
+			red.Printf("%-8s", finding.Severity)
+		default:
+			yellow.Printf("%-8s", finding.Severity)
+		}
+
+		fmt.Printf("%s: %s\n", finding.Location(), finding.Message)
+	}
+}
@@ -0,0 +1,109 @@
+package internal
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testCertificate returns self-signed PEM encoded certificate and its key
+func testCertificate(t *testing.T, subject pkix.Name, notAfter time.Time) ([]byte, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      subject,
+		DNSNames:     []string{"kubernetes.default"},
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	cert, key := testCertificate(t, pkix.Name{CommonName: "admin"}, time.Now().Add(time.Hour))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "key.pem"), key, 0600))
+
+	valid := Kubeconfig{
+		Clusters: []ClusterEntry{
+			{Name: "dev", Cluster: Cluster{
+				Server:                   "https://dev.example.com:6443",
+				CertificateAuthorityData: base64.StdEncoding.EncodeToString(cert),
+			}},
+		},
+		Contexts: []ContextEntry{
+			{Name: "dev", Context: Context{Cluster: "dev", User: "admin"}},
+		},
+		Users: []UserEntry{
+			{Name: "admin", User: User{
+				ClientCertificateData: base64.StdEncoding.EncodeToString(cert),
+				ClientKey:             "key.pem",
+			}},
+		},
+		CurrentContext: "dev",
+	}
+
+	require.Empty(t, Validate(valid, dir))
+
+	broken := Kubeconfig{
+		Clusters: []ClusterEntry{
+			{Name: "dev", Cluster: Cluster{Server: "dev.example.com", CertificateAuthorityData: "not base64"}},
+			{Name: "dev", Cluster: Cluster{Server: "https://dev.example.com", CertificateAuthority: "missing.crt"}},
+		},
+		Contexts: []ContextEntry{
+			{Name: "dev", Context: Context{Cluster: "prod", User: "admin"}},
+		},
+		Users: []UserEntry{
+			{Name: "admin", User: User{
+				ClientKeyData: base64.StdEncoding.EncodeToString([]byte("garbage")),
+				Exec:          &ExecConfig{APIVersion: "client.authentication.k8s.io/v1", Command: "./missing-plugin"},
+			}},
+		},
+		CurrentContext: "prod",
+	}
+
+	findings := Validate(broken, dir)
+	require.True(t, HasErrors(findings))
+
+	type location struct{ section, name, field string }
+	locations := []location{}
+	for _, finding := range findings {
+		require.Equal(t, SeverityError, finding.Severity)
+		locations = append(locations, location{finding.Section, finding.Name, finding.Field})
+	}
+
+	require.ElementsMatch(t, []location{
+		{"cluster", "dev", "name"},
+		{"current-context", "", ""},
+		{"context", "dev", "cluster"},
+		{"cluster", "dev", "server"},
+		{"cluster", "dev", "certificate-authority-data"},
+		{"cluster", "dev", "certificate-authority"},
+		{"user", "admin", "client-key-data"},
+		{"user", "admin", ""},
+		{"user", "admin", "exec.command"},
+	}, locations)
+}